@@ -14,6 +14,8 @@ import (
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 	"gopkg.in/yaml.v3"
+
+	"swissknife/internal/proc"
 )
 
 // Command represents a single command
@@ -63,14 +65,14 @@ func ExecuteCommand(ctx context.Context, cmd *Command, output *tview.TextView, m
 		select {
 		case <-ctx.Done():
 			// Stop execution if the context is canceled
-			// mu.Lock()
-			// cmd.Status = "Killed"
-			// cmd.Output = "Job terminated."
-			// content := fmt.Sprintf("Command: %s\nStatus: %s\nOutput:\n%s", cmd.Command, cmd.Status, cmd.Output)
-			// mu.Unlock()
-			// app.QueueUpdateDraw(func() {
-			// 	output.SetText(content)
-			// })
+			mu.Lock()
+			cmd.Status = "Killed"
+			cmd.Output = "Job terminated."
+			content := fmt.Sprintf("Command: %s\nStatus: %s\nOutput:\n%s", cmd.Command, cmd.Status, cmd.Output)
+			mu.Unlock()
+			proc.QueueDraw(ctx, app, func() {
+				output.SetText(content)
+			})
 			log.Println("cancelling", cmd.Command)
 			return
 		default:
@@ -78,7 +80,11 @@ func ExecuteCommand(ctx context.Context, cmd *Command, output *tview.TextView, m
 			execCmd := exec.Command("sh", "-c", cmd.Command)
 			execCmd.Stdout = &outputBuf
 			execCmd.Stderr = &outputBuf
-			err := execCmd.Run()
+			err := proc.Run(ctx, execCmd)
+			if ctx.Err() != nil {
+				// Killed mid-run, the ctx.Done() branch reports it
+				continue
+			}
 
 			status := "Completed"
 			if err != nil {
@@ -99,7 +105,7 @@ func ExecuteCommand(ctx context.Context, cmd *Command, output *tview.TextView, m
 			mu.Unlock()
 
 			// Refresh the TextView on the UI thread
-			app.QueueUpdateDraw(func() {
+			proc.QueueDraw(ctx, app, func() {
 				output.SetText(content)
 			})
 
@@ -0,0 +1,22 @@
+package main
+
+// paneAction is a request from the keyboard to the goroutine running a pane
+type paneAction int
+
+const (
+	actionRerun          paneAction = iota // r, R: run now, or right after the current run
+	actionTogglePause                      // space: stop or resume scheduled runs
+	actionKillRun                          // x: kill the run in flight
+	actionHistoryBack                      // [: show the run before the one shown
+	actionHistoryForward                   // ]: show the run after the one shown
+)
+
+// sendAction hands action to the pane without blocking the UI thread. The
+// pane may itself be waiting on the UI thread to draw, so if it is busy the
+// key press is dropped rather than risking a deadlock.
+func sendAction(actions chan<- paneAction, action paneAction) {
+	select {
+	case actions <- action:
+	default:
+	}
+}
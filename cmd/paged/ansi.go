@@ -0,0 +1,41 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// ansiSequence matches terminal escape sequences: CSI sequences such as
+// colors, OSC sequences such as hyperlinks and other escapes, such as the
+// character set choice `\x1b(B` that `tput sgr0` writes
+var ansiSequence = regexp.MustCompile(`\x1b(\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(\x07|\x1b\\)?|[ -/]*.)`)
+
+// renderOutput prepares command output for a TextView with dynamic colors.
+// Anything that looks like a tview tag is escaped so it shows as is, and
+// with translate set, ANSI colors are turned into tview tags
+func renderOutput(text string, translate bool) string {
+	if !translate {
+		return tview.Escape(text)
+	}
+
+	// Escape the text between sequences only, escaping the whole output
+	// could change a sequence such as `\x1b[1mfoo]`
+	var b strings.Builder
+	last := 0
+	for _, loc := range ansiSequence.FindAllStringIndex(text, -1) {
+		b.WriteString(tview.Escape(text[last:loc[0]]))
+		if text[loc[0]+1] == '[' {
+			b.WriteString(text[loc[0]:loc[1]])
+		}
+		last = loc[1]
+	}
+	b.WriteString(tview.Escape(text[last:]))
+	return tview.TranslateANSI(b.String())
+}
+
+// stripANSI removes terminal escape sequences from text
+func stripANSI(text string) string {
+	return ansiSequence.ReplaceAllString(text, "")
+}
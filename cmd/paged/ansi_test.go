@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestRenderOutput(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		translate bool
+		want      string
+	}{
+		{name: "color tag", text: "[red]", want: "[red[]"},
+		{name: "style tag", text: "[::b]", want: "[::b[]"},
+		{name: "translated color tag", text: "[red]", translate: true, want: "[red[]"},
+		{name: "bracket after a sequence", text: "\x1b[1mfoo]", translate: true, want: "[::b]foo]"},
+		{name: "colors and a tag", text: "\x1b[31mred\x1b[0m [blue]", translate: true, want: "[maroon:]red[-:-:-] [blue[]"},
+		{name: "hyperlink ended by BEL", text: "\x1b]8;;https://example.com\x07link\x1b]8;;\x07", translate: true, want: "link"},
+		{name: "hyperlink ended by ST", text: "\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\", translate: true, want: "link"},
+		{name: "character set", text: "\x1b(Bplain\x1b[m", translate: true, want: "plain[-:-:-]"},
+	}
+	for _, tt := range tests {
+		if got := renderOutput(tt.text, tt.translate); got != tt.want {
+			t.Errorf("%s: renderOutput(%q, %v) = %q, want %q", tt.name, tt.text, tt.translate, got, tt.want)
+		}
+	}
+}
+
+func TestStripANSI(t *testing.T) {
+	tests := map[string]string{
+		"\x1b[1;31merror\x1b[0m: [red]":                   "error: [red]",
+		"\x1b]8;;https://example.com\x07link\x1b]8;;\x07": "link",
+		"a\x1b[2Kb\x1b(Bc\x1b=":                           "abc",
+		"no sequences":                                    "no sequences",
+	}
+	for text, want := range tests {
+		if got := stripANSI(text); got != want {
+			t.Errorf("stripANSI(%q) = %q, want %q", text, got, want)
+		}
+	}
+
+	// The highlight path compares stripped runs, so colors alone changing
+	// highlights nothing and tags in the output stay escaped
+	prev, next := "\x1b[32mok\x1b[0m [1]", "\x1b[31mok\x1b[0m [2]"
+	if got, want := highlightChanges(stripANSI(prev), stripANSI(next)), "ok [[::r]2[::-]]"; got != want {
+		t.Errorf("highlighted %q, want %q", got, want)
+	}
+}
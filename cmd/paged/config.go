@@ -0,0 +1,130 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration read from YAML either as a Go duration string
+// ("500ms", "2m30s", "1h") or, for backwards compatibility, as a plain
+// integer number of seconds
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return nodeError(node, "expected a duration like 5s, 500ms or 2m30s")
+	}
+
+	var parsed time.Duration
+	if node.ShortTag() == "!!int" {
+		seconds, err := strconv.ParseInt(node.Value, 0, 64)
+		if err != nil {
+			return nodeError(node, "invalid duration %q: %v", node.Value, err)
+		}
+		parsed = time.Duration(seconds) * time.Second
+	} else {
+		var err error
+		parsed, err = time.ParseDuration(node.Value)
+		if err != nil {
+			return nodeError(node, "invalid duration %q, expected something like 5s, 500ms or 2m30s", node.Value)
+		}
+	}
+
+	if parsed < 0 {
+		return nodeError(node, "duration %q must not be negative", node.Value)
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// CommandLine is the `command` of a command: either a string run through
+// the shell, or a list run directly as argv, e.g. ["kubectl", "get", "pods"],
+// which needs no quoting and can't be subject to shell injection
+type CommandLine struct {
+	Script string
+	Argv   []string
+}
+
+func (c *CommandLine) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Decode(&c.Script)
+	case yaml.SequenceNode:
+		if err := node.Decode(&c.Argv); err != nil {
+			return err
+		}
+		if len(c.Argv) == 0 || c.Argv[0] == "" {
+			return nodeError(node, "command list must start with the program to run")
+		}
+		return nil
+	default:
+		return nodeError(node, "command must be a string or a list of arguments")
+	}
+}
+
+// defaultShell runs commands when neither the command nor the file's
+// defaults pick a shell
+var defaultShell = []string{"sh", "-c"}
+
+// YAMLDefaults is the file-level `defaults` block, inherited by every
+// command in the file
+type YAMLDefaults struct {
+	Env   map[string]string `yaml:"env"`
+	Cwd   string            `yaml:"cwd"`
+	Shell string            `yaml:"shell"`
+}
+
+// resolveShell splits the shell a command runs with, e.g. "bash -lc", into
+// the argv the command string is appended to
+func resolveShell(defaults YAMLDefaults, yamlCmd YAMLCommand) []string {
+	shell := yamlCmd.Shell
+	if shell == "" {
+		shell = defaults.Shell
+	}
+	if argv := strings.Fields(shell); len(argv) > 0 {
+		return argv
+	}
+	return defaultShell
+}
+
+// resolveEnv layers the command's env over the file defaults and returns it
+// as sorted KEY=value pairs to append to the inherited environment
+func resolveEnv(defaults YAMLDefaults, yamlCmd YAMLCommand) []string {
+	merged := make(map[string]string, len(defaults.Env)+len(yamlCmd.Env))
+	maps.Copy(merged, defaults.Env)
+	maps.Copy(merged, yamlCmd.Env)
+
+	env := make([]string, 0, len(merged))
+	for key, value := range merged {
+		env = append(env, key+"="+value)
+	}
+	slices.Sort(env)
+	return env
+}
+
+// resolveCwd returns the directory the command runs in. Environment
+// variables are expanded and relative paths are taken from the directory of
+// the yaml file, so a page works the same wherever it is launched from
+func resolveCwd(defaults YAMLDefaults, yamlCmd YAMLCommand, configDir string) string {
+	cwd := yamlCmd.Cwd
+	if cwd == "" {
+		cwd = defaults.Cwd
+	}
+	if cwd == "" {
+		return ""
+	}
+
+	cwd = os.ExpandEnv(cwd)
+	if !filepath.IsAbs(cwd) {
+		cwd = filepath.Join(configDir, cwd)
+	}
+	return cwd
+}
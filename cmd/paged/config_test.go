@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestDurationUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    time.Duration
+		wantErr string
+	}{
+		{text: "5", want: 5 * time.Second},
+		{text: "500ms", want: 500 * time.Millisecond},
+		{text: "2m30s", want: 150 * time.Second},
+		{text: `"10"`, wantErr: `1:1: invalid duration "10", expected something like 5s, 500ms or 2m30s`},
+		{text: "-5s", wantErr: `1:1: duration "-5s" must not be negative`},
+		{text: "-5", wantErr: `1:1: duration "-5" must not be negative`},
+		{text: "soon", wantErr: `1:1: invalid duration "soon", expected something like 5s, 500ms or 2m30s`},
+		{text: "[5s]", wantErr: "1:1: expected a duration like 5s, 500ms or 2m30s"},
+	}
+	for _, tt := range tests {
+		var d Duration
+		err := yaml.Unmarshal([]byte(tt.text), &d)
+		if tt.wantErr != "" {
+			var typeErr *yaml.TypeError
+			if !errors.As(err, &typeErr) || len(typeErr.Errors) != 1 || typeErr.Errors[0] != tt.wantErr {
+				t.Errorf("%s: got error %v, want %q", tt.text, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || time.Duration(d) != tt.want {
+			t.Errorf("%s: got %v, %v, want %v", tt.text, time.Duration(d), err, tt.want)
+		}
+	}
+}
+
+func TestCommandLineUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    CommandLine
+		wantErr string
+	}{
+		{text: "kubectl get pods | wc -l", want: CommandLine{Script: "kubectl get pods | wc -l"}},
+		{text: "[kubectl, get, pods]", want: CommandLine{Argv: []string{"kubectl", "get", "pods"}}},
+		{text: `["", get]`, wantErr: "1:1: command list must start with the program to run"},
+		{text: "[]", wantErr: "1:1: command list must start with the program to run"},
+		{text: "{run: date}", wantErr: "1:1: command must be a string or a list of arguments"},
+	}
+	for _, tt := range tests {
+		var c CommandLine
+		err := yaml.Unmarshal([]byte(tt.text), &c)
+		if tt.wantErr != "" {
+			var typeErr *yaml.TypeError
+			if !errors.As(err, &typeErr) || len(typeErr.Errors) != 1 || typeErr.Errors[0] != tt.wantErr {
+				t.Errorf("%s: got error %v, want %q", tt.text, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(c, tt.want) {
+			t.Errorf("%s: got %+v, %v, want %+v", tt.text, c, err, tt.want)
+		}
+	}
+}
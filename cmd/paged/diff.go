@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// highlightChanges renders next with every character that differs from the
+// same position in prev shown in reverse video, the way `watch -d` does.
+// Lines past the end of prev are highlighted whole. The result is escaped
+// for a tview TextView with dynamic colors
+func highlightChanges(prev, next string) string {
+	prevLines := strings.Split(prev, "\n")
+	nextLines := strings.Split(next, "\n")
+
+	var b strings.Builder
+	for i, line := range nextLines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		var old []rune
+		if i < len(prevLines) {
+			old = []rune(prevLines[i])
+		}
+
+		runes := []rune(line)
+		start := 0
+		for start < len(runes) {
+			changed := start >= len(old) || runes[start] != old[start]
+			end := start + 1
+			for end < len(runes) && (end >= len(old) || runes[end] != old[end]) == changed {
+				end++
+			}
+
+			segment := tview.Escape(string(runes[start:end]))
+			if changed {
+				b.WriteString("[::r]" + segment + "[::-]")
+			} else {
+				b.WriteString(segment)
+			}
+			start = end
+		}
+	}
+	return b.String()
+}
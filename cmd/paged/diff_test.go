@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestHighlightChanges(t *testing.T) {
+	tests := []struct {
+		prev, next, want string
+	}{
+		{prev: "abc", next: "abc", want: "abc"},
+		{prev: "abc", next: "abd", want: "ab[::r]d[::-]"},
+		{prev: "abc", next: "xbz", want: "[::r]x[::-]b[::r]z[::-]"},
+		{prev: "ab", next: "abcd", want: "ab[::r]cd[::-]"},
+		{prev: "a\nb", next: "a\nc\nd", want: "a\n[::r]c[::-]\n[::r]d[::-]"},
+		{prev: "[red]", next: "[rod]", want: "[r[::r]o[::-]d]"},
+		{prev: "", next: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := highlightChanges(tt.prev, tt.next); got != tt.want {
+			t.Errorf("highlightChanges(%q, %q) = %q, want %q", tt.prev, tt.next, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import "time"
+
+// defaultHistory is the number of finished runs kept per command
+const defaultHistory = 20
+
+// RunRecord is the outcome of one finished run, as kept in a command's history
+type RunRecord struct {
+	Run        int
+	Status     string
+	ExitCode   int
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Duration   time.Duration
+	Output     string // Stdout of this run, even if it failed
+	Stderr     string
+}
+
+// runHistory is a ring buffer of the most recent runs of a command
+type runHistory struct {
+	runs []RunRecord
+	next int // Slot the next run is written to
+	size int
+}
+
+func newRunHistory(capacity int) *runHistory {
+	return &runHistory{runs: make([]RunRecord, capacity)}
+}
+
+// Add stores run, dropping the oldest one once the buffer is full
+func (h *runHistory) Add(run RunRecord) {
+	h.runs[h.next] = run
+	h.next = (h.next + 1) % len(h.runs)
+	if h.size < len(h.runs) {
+		h.size++
+	}
+}
+
+// Len returns the number of runs kept
+func (h *runHistory) Len() int {
+	return h.size
+}
+
+// Back returns the run n steps before the latest one, 0 being the latest
+func (h *runHistory) Back(n int) (RunRecord, bool) {
+	if n < 0 || n >= h.size {
+		return RunRecord{}, false
+	}
+	return h.runs[(h.next-1-n+len(h.runs))%len(h.runs)], true
+}
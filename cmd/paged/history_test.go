@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestRunHistoryBack(t *testing.T) {
+	h := newRunHistory(3)
+	if _, ok := h.Back(0); ok {
+		t.Error("an empty history has a latest run")
+	}
+
+	for run := 1; run <= 5; run++ {
+		h.Add(RunRecord{Run: run})
+	}
+	if h.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", h.Len())
+	}
+	// Runs 1 and 2 were dropped when the ring wrapped around
+	for n, want := range []int{5, 4, 3} {
+		run, ok := h.Back(n)
+		if !ok || run.Run != want {
+			t.Errorf("Back(%d) = run %d, %v, want run %d", n, run.Run, ok, want)
+		}
+	}
+	for _, n := range []int{-1, 3} {
+		if _, ok := h.Back(n); ok {
+			t.Errorf("Back(%d) found a run", n)
+		}
+	}
+}
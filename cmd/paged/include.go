@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is a commands file read along with the files it includes
+type configFile struct {
+	doc      *yaml.Node // Top-level mapping of the file
+	config   YAMLConfig
+	commands []sourceCommand       // Commands of the included files first, then the file's own
+	snippets map[string]*yaml.Node // Snippets of the file and the files it includes
+	vars     map[string]string     // Vars of the file and the files it includes
+}
+
+// sourceCommand is a command along with the file it is written in, whose
+// defaults it inherits and whose directory relative paths start from
+type sourceCommand struct {
+	node     *yaml.Node // Command mapping, with its snippet merged in
+	file     string
+	defaults *yaml.Node // The file's defaults, nil if it has none
+}
+
+// loadConfigFile reads filename and, recursively, the files it includes.
+// Problems in the settings go to errs, the error returned is for a file
+// that can't be read at all. stack holds the files including this one, for
+// cycle detection, and loaded every file of the page read so far, by
+// absolute path
+func loadConfigFile(filename string, stack []string, loaded map[string]*configFile, errs *configErrors) (*configFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open YAML file: %v", err)
+	}
+
+	// Decode through the node tree so that problems can be reported with
+	// their line and column, all of them at once
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	doc := &yaml.Node{Kind: yaml.MappingNode, Line: 1, Column: 1}
+	if len(root.Content) > 0 {
+		doc = root.Content[0]
+	}
+	errs.register(doc, filename)
+	checkFields(doc, reflect.TypeOf(YAMLConfig{}), errs)
+
+	file := &configFile{doc: doc, snippets: make(map[string]*yaml.Node), vars: make(map[string]string)}
+	loaded[absPath(filename)] = file
+	if err := doc.Decode(&file.config); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+		errs.addTypeError(filename, typeErr)
+	}
+
+	// Included files come first, so the file's own snippets and vars win
+	stack = append(stack, filename)
+	for i, include := range file.config.Include {
+		node := sequenceItem(mappingValue(doc, "include"), i)
+		path := include
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(filename), path)
+		}
+		if slices.ContainsFunc(stack, func(f string) bool { return sameFile(f, path) }) {
+			errs.add(node, "include cycle: %s -> %s", strings.Join(stack, " -> "), path)
+			continue
+		}
+
+		// A file included along two paths, e.g. by two files that are both
+		// included here, brings its commands to the page only once. Its
+		// snippets and vars are still there for each file including it
+		included, ok := loaded[absPath(path)]
+		if !ok {
+			included, err = loadConfigFile(path, stack, loaded, errs)
+			if err != nil {
+				errs.add(node, "%v", err)
+				continue
+			}
+			file.commands = append(file.commands, included.commands...)
+		}
+		maps.Copy(file.snippets, included.snippets)
+		maps.Copy(file.vars, included.vars)
+	}
+	maps.Copy(file.vars, file.config.Vars)
+
+	if snippets := mappingValue(doc, "snippets"); snippets != nil && snippets.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(snippets.Content); i += 2 {
+			name, snippet := snippets.Content[i], snippets.Content[i+1]
+			if !checkCommandNode(snippet, filename, errs) {
+				continue
+			}
+			if use := mappingValue(snippet, "use"); use != nil {
+				errs.add(use, "a snippet can't use another snippet")
+				continue
+			}
+			file.snippets[name.Value] = snippet
+		}
+	}
+
+	if commands := mappingValue(doc, "commands"); commands != nil && commands.Kind == yaml.SequenceNode {
+		for _, node := range commands.Content {
+			if !checkCommandNode(node, filename, errs) {
+				continue
+			}
+			if use := mappingValue(node, "use"); use != nil {
+				snippet, ok := file.snippets[use.Value]
+				if !ok {
+					errs.add(use, "no snippet named %q", use.Value)
+					continue
+				}
+				node = mergeSnippet(snippet, node)
+				errs.files[node] = filename
+			}
+			file.commands = append(file.commands, sourceCommand{
+				node:     node,
+				file:     filename,
+				defaults: mappingValue(doc, "defaults"),
+			})
+		}
+	}
+
+	return file, nil
+}
+
+// checkCommandNode reports unknown fields and values of the wrong type in
+// the settings of a command or snippet, and whether node is a mapping at all
+func checkCommandNode(node *yaml.Node, filename string, errs *configErrors) bool {
+	if node.Kind != yaml.MappingNode {
+		errs.add(node, "expected a mapping of command settings")
+		return false
+	}
+	checkFields(node, reflect.TypeOf(YAMLCommand{}), errs)
+
+	var yamlCmd YAMLCommand
+	if err := node.Decode(&yamlCmd); err != nil {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			errs.addTypeError(filename, typeErr)
+		} else {
+			errs.add(node, "%v", err)
+		}
+	}
+	return true
+}
+
+// mergeSnippet returns the settings of snippet with those of the command
+// using it laid over them
+func mergeSnippet(snippet, command *yaml.Node) *yaml.Node {
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: command.Tag, Line: command.Line, Column: command.Column}
+	for i := 0; i+1 < len(snippet.Content); i += 2 {
+		if mappingValue(command, snippet.Content[i].Value) == nil {
+			merged.Content = append(merged.Content, snippet.Content[i], snippet.Content[i+1])
+		}
+	}
+	merged.Content = append(merged.Content, command.Content...)
+	return merged
+}
+
+// sequenceItem returns item i of a sequence node, or node itself if there is
+// no such item
+func sequenceItem(node *yaml.Node, i int) *yaml.Node {
+	if node != nil && node.Kind == yaml.SequenceNode && i < len(node.Content) {
+		return node.Content[i]
+	}
+	return node
+}
+
+// absPath returns path made absolute, or only cleaned if that fails
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// sameFile reports whether a and b name the same file
+func sameFile(a, b string) bool {
+	aInfo, aErr := os.Stat(a)
+	bInfo, bErr := os.Stat(b)
+	if aErr != nil || bErr != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return os.SameFile(aInfo, bInfo)
+}
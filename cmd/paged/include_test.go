@@ -0,0 +1,89 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func commandNames(commands []*Command) []string {
+	var names []string
+	for _, cmd := range commands {
+		names = append(names, cmd.Name)
+	}
+	return names
+}
+
+func TestLoadCommandsIncludes(t *testing.T) {
+	file := writeConfig(t,
+		"top.yaml", `
+include: [b.yaml, c.yaml]
+commands:
+  - use: pods
+    name: top
+`,
+		"b.yaml", `
+include: [d.yaml]
+commands:
+  - name: b
+    command: echo b
+`,
+		"c.yaml", `
+include: [d.yaml]
+commands:
+  - use: pods
+    name: c
+`,
+		"d.yaml", `
+snippets:
+  pods:
+    command: kubectl get pods
+commands:
+  - name: shared
+    command: echo shared
+`)
+
+	commands, settings, err := LoadCommandsFromYAML(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"shared", "b", "c", "top"}
+	if got := commandNames(commands); !slices.Equal(got, want) {
+		t.Errorf("commands = %v, want %v", got, want)
+	}
+	var files []string
+	for _, file := range settings.Files {
+		files = append(files, filepath.Base(file))
+	}
+	if want := []string{"b.yaml", "c.yaml", "d.yaml", "top.yaml"}; !slices.Equal(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+	if got := commands[2].Command; got != "kubectl get pods" {
+		t.Errorf("command using a snippet of a file included twice = %q", got)
+	}
+}
+
+func TestLoadCommandsIncludeCycle(t *testing.T) {
+	file := writeConfig(t,
+		"a.yaml", `
+include: [b.yaml]
+commands:
+  - name: a
+    command: echo a
+`,
+		"b.yaml", `
+include: [a.yaml]
+commands:
+  - name: b
+    command: echo b
+`)
+
+	_, _, err := LoadCommandsFromYAML(file, nil)
+	if err == nil {
+		t.Fatal("loaded files including each other")
+	}
+	if want := "b.yaml:2:11: include cycle"; !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/rivo/tview"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultGridMinWidth is the narrowest a grid column gets before the grid
+// drops a column
+const defaultGridMinWidth = 40
+
+// LayoutNode is one box of an explicit page layout: either a command's pane,
+// or rows or columns of nested boxes. The whole layout can be a grid instead
+//
+//	layout:
+//	  rows:
+//	    - pane: pods
+//	      size: 12
+//	    - columns:
+//	        - pane: logs
+//	          proportion: 2
+//	        - pane: disk
+type LayoutNode struct {
+	Pane       string        `yaml:"pane"`       // id of the command shown, every pane of a matrix shares its id
+	Rows       []*LayoutNode `yaml:"rows"`       // boxes stacked top to bottom
+	Columns    []*LayoutNode `yaml:"columns"`    // boxes side by side
+	Grid       *GridLayout   `yaml:"grid"`       // every pane in a grid, in the order of the commands
+	Size       int           `yaml:"size"`       // fixed height or width in cells, 0 to share the space left
+	Proportion int           `yaml:"proportion"` // share of the space left, defaults to 1
+
+	node *yaml.Node // where the box is written, for reporting problems
+}
+
+// UnmarshalYAML accepts `layout: grid` as a grid with as many columns as
+// fit, next to the mapping form
+func (n *LayoutNode) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		if node.Value != "grid" {
+			return nodeError(node, "unknown layout %q, expected grid or a mapping", node.Value)
+		}
+		*n = LayoutNode{Grid: &GridLayout{}, node: node}
+		return nil
+	}
+
+	type plain LayoutNode
+	err := node.Decode((*plain)(n))
+	n.node = node
+	return err
+}
+
+// GridLayout arranges every pane of a page in a grid, left to right and top
+// to bottom. On a narrow terminal, the grid drops columns until each is at
+// least MinWidth wide
+type GridLayout struct {
+	Columns  GridColumns `yaml:"columns"`   // most columns shown, 0 for as many as fit
+	MinWidth int         `yaml:"min_width"` // narrowest column, defaults to defaultGridMinWidth
+}
+
+// GridColumns is a number of grid columns, read from YAML as a number or as
+// "auto" for as many as fit
+type GridColumns int
+
+func (c *GridColumns) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && node.Value == "auto" {
+		*c = 0
+		return nil
+	}
+	columns, err := strconv.Atoi(node.Value)
+	if node.Kind != yaml.ScalarNode || err != nil || columns < 1 {
+		return nodeError(node, "grid columns must be a positive number or auto, got %q", node.Value)
+	}
+	*c = GridColumns(columns)
+	return nil
+}
+
+// validate reports every problem with the boxes below n and records which
+// command ids they place
+func (n *LayoutNode) validate(ids map[string]*yaml.Node, placed map[string]bool, errs *configErrors) {
+	if n.Grid != nil {
+		errs.add(fieldNode(n.node, "grid"), "a grid can only be the whole layout")
+		return
+	}
+
+	kinds := 0
+	for _, set := range []bool{n.Pane != "", len(n.Rows) > 0, len(n.Columns) > 0} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		errs.add(n.node, "each box of the layout needs exactly one of pane, rows or columns")
+	}
+	if n.Size < 0 {
+		errs.add(fieldNode(n.node, "size"), "size must not be negative, got %d", n.Size)
+	}
+	if n.Proportion < 0 {
+		errs.add(fieldNode(n.node, "proportion"), "proportion must not be negative, got %d", n.Proportion)
+	}
+
+	if n.Pane != "" {
+		pane := fieldNode(n.node, "pane")
+		switch {
+		case ids[n.Pane] == nil:
+			errs.add(pane, "no command with id %q", n.Pane)
+		case placed[n.Pane]:
+			errs.add(pane, "command %q is placed more than once", n.Pane)
+		default:
+			placed[n.Pane] = true
+		}
+	}
+
+	for _, child := range append(n.Rows, n.Columns...) {
+		child.validate(ids, placed, errs)
+	}
+}
+
+// panes returns the command ids in the layout, top to bottom and left to right
+func (n *LayoutNode) panes() []string {
+	if n.Pane != "" {
+		return []string{n.Pane}
+	}
+	var ids []string
+	for _, child := range append(n.Rows, n.Columns...) {
+		ids = append(ids, child.panes()...)
+	}
+	return ids
+}
+
+// validateLayout reports every problem with layout, and every command it
+// doesn't place. ids holds where the id of each command is set
+func validateLayout(layout *LayoutNode, ids map[string]*yaml.Node, errs *configErrors) {
+	if grid := layout.Grid; grid != nil {
+		if layout.Pane != "" || len(layout.Rows) > 0 || len(layout.Columns) > 0 {
+			errs.add(layout.node, "a grid can't have pane, rows or columns next to it")
+		}
+		if grid.MinWidth < 0 {
+			errs.add(fieldNode(fieldNode(layout.node, "grid"), "min_width"), "grid min_width must not be negative, got %d", grid.MinWidth)
+		}
+		return
+	}
+
+	placed := make(map[string]bool)
+	layout.validate(ids, placed, errs)
+	for id, node := range ids {
+		if !placed[id] {
+			errs.add(node, "command %q is not placed in the layout", id)
+		}
+	}
+}
+
+// LayoutGroup puts commands in a single group, in the order layout shows them
+func LayoutGroup(commands []*Command, layout *LayoutNode) *Group {
+	if layout.Grid != nil {
+		return &Group{Placed: commands}
+	}
+
+	byID := make(map[string][]*Command, len(commands))
+	for _, cmd := range commands {
+		byID[cmd.ID] = append(byID[cmd.ID], cmd)
+	}
+
+	group := &Group{}
+	for _, id := range layout.panes() {
+		group.Placed = append(group.Placed, byID[id]...)
+	}
+	return group
+}
+
+// createLayoutFlex creates the panes of the single layout group and arranges
+// them as state.Layout says
+func createLayoutFlex(state *AppState) *tview.Flex {
+	var panes []tview.Primitive
+	views := make(map[string][]tview.Primitive)
+	state.TextViews[0] = make([]*tview.TextView, 0)
+	for _, cmd := range state.Groups[0].Commands() {
+		pane := createPane(state, 0, cmd)
+		panes = append(panes, pane)
+		views[cmd.ID] = append(views[cmd.ID], pane)
+	}
+
+	var root tview.Primitive
+	if state.Layout.Grid != nil {
+		root = createGrid(state.Layout.Grid, panes)
+	} else {
+		root = layoutItem(state.Layout, views)
+	}
+	if flex, ok := root.(*tview.Flex); ok {
+		return flex
+	}
+	// A layout of a single pane
+	return tview.NewFlex().AddItem(root, 0, 1, false)
+}
+
+// layoutItem arranges the box n. The panes of a matrix share a box, in a grid
+func layoutItem(n *LayoutNode, views map[string][]tview.Primitive) tview.Primitive {
+	if n.Pane != "" {
+		if panes := views[n.Pane]; len(panes) > 1 {
+			return createGrid(&GridLayout{}, panes)
+		}
+		return views[n.Pane][0]
+	}
+
+	flex := tview.NewFlex().SetDirection(tview.FlexColumn)
+	children := n.Columns
+	if len(n.Rows) > 0 {
+		flex.SetDirection(tview.FlexRow)
+		children = n.Rows
+	}
+	for _, child := range children {
+		proportion := child.Proportion
+		if proportion == 0 {
+			proportion = 1
+		}
+		flex.AddItem(layoutItem(child, views), child.Size, proportion, false)
+	}
+	return flex
+}
+
+// createGrid arranges panes in a grid of up to layout.Columns columns. Each
+// column count from 1 up is added as a tview breakpoint, so the grid shows
+// the most columns that are each at least layout.MinWidth wide
+func createGrid(layout *GridLayout, panes []tview.Primitive) *tview.Grid {
+	minWidth := layout.MinWidth
+	if minWidth == 0 {
+		minWidth = defaultGridMinWidth
+	}
+	maxColumns := int(layout.Columns)
+	if maxColumns == 0 || maxColumns > len(panes) {
+		maxColumns = len(panes)
+	}
+
+	grid := tview.NewGrid()
+	for columns := 1; columns <= maxColumns; columns++ {
+		minGridWidth := 0
+		if columns > 1 {
+			minGridWidth = columns * minWidth
+		}
+		for i, pane := range panes {
+			grid.AddItem(pane, i/columns, i%columns, 1, 1, 0, minGridWidth, false)
+		}
+	}
+	return grid
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadCommandsLayoutErrors(t *testing.T) {
+	file := writeConfig(t, "a.yaml", `
+commands:
+  - id: a
+    name: A
+    command: date
+  - id: b
+    name: B
+    command: date
+  - name: C
+    command: date
+layout:
+  rows:
+    - pane: a
+      size: -1
+    - columns:
+        - pane: nope
+        - pane: a
+`)
+
+	_, _, err := LoadCommandsFromYAML(file, nil)
+	if err == nil {
+		t.Fatal("loaded an invalid layout")
+	}
+	want := []string{
+		`a.yaml:6:9: command "b" is not placed in the layout`,
+		`a.yaml:9:5: command "C" needs an id to be placed in the layout`,
+		`a.yaml:14:13: size must not be negative, got -1`,
+		`a.yaml:16:17: no command with id "nope"`,
+		`a.yaml:17:17: command "a" is placed more than once`,
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("got %d problems, want %d:\n%v", len(lines), len(want), err)
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, want[i]) {
+			t.Errorf("problem %d = %q, want %q", i, line, want[i])
+		}
+	}
+}
+
+func TestLayoutGroupOrder(t *testing.T) {
+	file := writeConfig(t, "a.yaml", `
+commands:
+  - id: w
+    name: "w {{.n}}"
+    matrix: {n: [1, 2]}
+    command: date
+  - id: d
+    name: d
+    command: date
+layout:
+  columns:
+    - pane: d
+    - pane: w
+`)
+
+	commands, settings, err := LoadCommandsFromYAML(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group := LayoutGroup(commands, settings.Layout)
+	if got, want := commandNames(group.Commands()), []string{"d", "w 1", "w 2"}; !slices.Equal(got, want) {
+		t.Errorf("panes = %v, want %v", got, want)
+	}
+}
@@ -5,39 +5,101 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"maps"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"github.com/robfig/cron/v3"
 	"gopkg.in/yaml.v3"
+
+	"swissknife/internal/proc"
 )
 
 // Command represents a single command
 type Command struct {
-	Name      string
-	Command   string
-	Repeat    int // Interval in seconds for repeating jobs (0 = run once)
-	Output    string
-	Status    string
-	IsRunning bool
+	ID         string // Referenced by an explicit layout
+	Name       string
+	Command    string
+	Argv       []string      // Run directly instead of through the shell when set
+	Shell      []string      // argv the command string is appended to, e.g. sh -c
+	Env        []string      // KEY=value pairs added to the inherited environment
+	Cwd        string        // Working directory, empty for swissknife's own
+	Repeat     time.Duration // Interval for repeating jobs (0 = run once)
+	Schedule   cron.Schedule // Cron schedule, used instead of Repeat when set
+	Timeout    time.Duration // Upper bound for a single run (0 = no limit)
+	Overlap    string        // What a tick does while the previous run is still going
+	Jitter     time.Duration // Random offset applied to the repeat schedule
+	Type       string        // TypeTerminal for an interactive program, empty otherwise
+	Mode       string        // ModeStream to show output as it is produced
+	Scrollback int           // Lines kept by a streaming pane
+	Restart    string        // Restart policy once a stream exits
+	HideStderr bool          // Leave stderr out of the pane
+	Highlight  bool          // Mark what changed since the previous run
+	ANSI       bool          // Translate ANSI colors in the output
+	PTY        bool          // Run under a pseudo-terminal, stderr ends up in the output
+	Term       *termSize     // Size of the pseudo-terminal, nil unless PTY
+	Retry      *RetryPolicy  // Retries for failed runs, nil to fail right away
+	Retrying   string        // e.g. "retry 2/5 in 4s" while waiting to retry
+	Priority   int           // Runs with a higher priority get a free slot first
+	Queued     bool          // Waiting for a slot in the worker pool
+	Paused     bool          // Scheduled runs are skipped until resumed
+	Output     string        // Stdout of the last successful run
+	Changes    string        // Output with the changes since the run before marked
+	ChangedAt  time.Time     // When Output last differed from the run before
+	Stderr     string        // Stderr of the last run
+	Status     string        // One of the Status* outcomes of the last run
+	IsRunning  bool
+	ExitCode   int // -1 when the process didn't exit on its own
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Duration   time.Duration
+	Failures   int         // Consecutive failed runs
+	Runs       int         // Total finished runs
+	History    *runHistory // Most recent finished runs
+	Viewing    int         // Steps back in History shown in the pane (0 = live)
+	Matrix     *Matrix     // Matrix the command was expanded from, shared with the other panes
+	Spec       commandSpec // What the command was loaded from, to tell if a reload changed it
 }
 
 // Group represents a group of commands
 type Group struct {
 	Repeating    []*Command
 	NonRepeating []*Command
+	Matrix       []*Command // Panes expanded from a single matrix, shown in a grid
+	Placed       []*Command // All commands, in layout order, when the page has an explicit layout
+}
+
+// Commands returns the commands of the group in the order of its panes
+func (g *Group) Commands() []*Command {
+	if g.Placed != nil {
+		return g.Placed
+	}
+	return slices.Concat(g.Repeating, g.NonRepeating, g.Matrix)
+}
+
+// paneView is a pane focus cycles through, a TextView or a terminal pane
+type paneView interface {
+	tview.Primitive
+	SetBorderAttributes(attr tcell.AttrMask) *tview.Box
 }
 
 // AppState holds the app's state
 type AppState struct {
 	Groups      []*Group
-	TextViews   [][]*tview.TextView
+	TextViews   [][]*tview.TextView // nil where a terminal pane takes the slot
+	Terminals   map[[2]int]*terminalPane
+	Layout      *LayoutNode // Explicit layout of the single group, nil for the default grouping
 	CancelFuncs map[[2]int]context.CancelFunc
+	Actions     map[[2]int]chan paneAction
+	Kept        map[*Command]runningPane // Panes still running from before a reload
 	Mu          sync.Mutex
 }
 
@@ -58,71 +120,407 @@ func init() {
 	log.SetOutput(logFile)
 }
 
-// ExecuteCommand runs the command and updates the output
-func ExecuteCommand(ctx context.Context, cmd *Command, output *tview.TextView, mu *sync.Mutex, app *tview.Application) {
+// ExecuteCommand runs the command and updates the output. Repeating commands
+// are run on wall-clock aligned ticks, or whenever their cron schedule comes
+// up, until ctx is cancelled. Keyboard actions for the pane arrive on actions
+func ExecuteCommand(ctx context.Context, cmd *Command, output *tview.TextView, mu *sync.Mutex, app *tview.Application, pool *workerPool, actions <-chan paneAction) {
+	// Run-once commands never tick, but stay around to be rerun
+	var ticks *ticker
+	var tickC, countdown <-chan time.Time
+	switch {
+	case cmd.Schedule != nil:
+		ticks = newCronTicker(cmd.Schedule, cmd.Jitter)
+
+		// Keep the time left until the next run up to date in the title
+		everySecond := time.NewTicker(time.Second)
+		defer everySecond.Stop()
+		countdown = everySecond.C
+	case cmd.Repeat > 0:
+		ticks = newIntervalTicker(cmd.Repeat, cmd.Jitter)
+	}
+	if ticks != nil {
+		defer ticks.Stop()
+		tickC = ticks.C
+	}
+
+	done := make(chan struct{})
+	running, queued, paused, killRequested := false, false, false, false
+	cancelRun := context.CancelFunc(func() {})
+	start := func() {
+		var runCtx context.Context
+		runCtx, cancelRun = context.WithCancel(ctx)
+		running = true
+		go func() {
+			runCommand(runCtx, cmd, output, mu, app, pool)
+			done <- struct{}{}
+		}()
+	}
+
+	if cmd.Schedule == nil {
+		start()
+	} else {
+		// Scheduled commands are usually expensive, so they wait for their slot
+		proc.QueueDraw(ctx, app, func() {
+			output.SetText("Waiting for the first scheduled run")
+		})
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
-			// Stop execution if the context is canceled
-			// mu.Lock()
-			// cmd.Status = "Killed"
-			// cmd.Output = "Job terminated."
-			// content := fmt.Sprintf("Command: %s\nStatus: %s\nOutput:\n%s", cmd.Command, cmd.Status, cmd.Output)
-			// mu.Unlock()
-			// app.QueueUpdateDraw(func() {
-			// 	output.SetText(content)
-			// })
-			// log.Println("cancelling", cmd.Command)
+			// The run, if any, was killed along with ctx
+			if running {
+				<-done
+			}
+			cancelRun()
+			reportKilled(ctx, cmd, output, mu, app)
 			return
-		default:
-			var outputBuf bytes.Buffer
-			execCmd := exec.Command("sh", "-c", cmd.Command)
-			execCmd.Stdout = &outputBuf
-			execCmd.Stderr = &outputBuf
-			err := execCmd.Run()
+		case <-done:
+			running = false
+			cancelRun()
+			if killRequested {
+				killRequested = false
+				reportRunKilled(ctx, cmd, output, mu, app)
+			}
+			if queued {
+				queued = false
+				start()
+			}
+		case <-tickC:
+			if paused {
+				continue
+			}
+			if !running {
+				start()
+				continue
+			}
 
-			status := fmt.Sprintf("Completed: %s", time.Now().Format(time.RFC1123))
-			if err != nil {
-				status = fmt.Sprintf("Failed: %s", time.Now().Format(time.RFC1123))
+			switch cmd.Overlap {
+			case OverlapQueue:
+				queued = true
+			case OverlapKillPrevious:
+				log.Println("killing overrunning", cmd.Command)
+				cancelRun()
+				<-done
+				start()
+			default:
+				log.Println("skipping tick, still running", cmd.Command)
+			}
+		case <-countdown:
+			mu.Lock()
+			title := paneTitle(cmd)
+			waiting := cmd.Retrying != "" || cmd.Queued || cmd.Paused
+			mu.Unlock()
+
+			switch next := ticks.Next(); {
+			case waiting:
+			case running:
+				title += " · running"
+			case next.IsZero():
+				title += " · no more runs"
+			default:
+				title += " · " + formatCountdown(next)
+			}
+			proc.QueueDraw(ctx, app, func() {
+				output.SetTitle(title)
+			})
+		case action := <-actions:
+			switch action {
+			case actionRerun:
+				if running {
+					queued = true
+					continue
+				}
+				start()
+			case actionKillRun:
+				if running {
+					killRequested, queued = true, false
+					cancelRun()
+				}
+			case actionHistoryBack, actionHistoryForward:
+				mu.Lock()
+				if action == actionHistoryBack {
+					cmd.Viewing = min(cmd.Viewing+1, max(cmd.History.Len()-1, 0))
+				} else {
+					cmd.Viewing = max(cmd.Viewing-1, 0)
+				}
+				content := formatContent(cmd)
+				title := paneTitle(cmd)
+				mu.Unlock()
+
+				proc.QueueDraw(ctx, app, func() {
+					output.SetText(content)
+					output.SetTitle(title)
+				})
+			case actionTogglePause:
+				if ticks == nil {
+					// Nothing scheduled to pause
+					continue
+				}
+
+				paused = !paused
+				mu.Lock()
+				cmd.Paused = paused
+				title := paneTitle(cmd)
+				mu.Unlock()
+
+				color := tcell.ColorGreen
+				if paused {
+					color = tcell.ColorGray
+				}
+				proc.QueueDraw(ctx, app, func() {
+					output.SetTitle(title)
+					output.SetBorderColor(color)
+				})
 			}
+		}
+	}
+}
+
+// runCommand runs the command once, retrying it according to cmd.Retry, and
+// shows the outcome in the pane. Each attempt waits for a slot in pool. It
+// returns early, without touching the pane, if ctx is cancelled mid-run
+func runCommand(ctx context.Context, cmd *Command, output *tview.TextView, mu *sync.Mutex, app *tview.Application, pool *workerPool) {
+	defer func() {
+		mu.Lock()
+		cmd.Retrying = ""
+		mu.Unlock()
+	}()
+
+	attempts := 1
+	if cmd.Retry != nil {
+		attempts = cmd.Retry.Attempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		var stdoutBuf, stderrBuf bytes.Buffer
+		execCmd := buildExecCmd(cmd)
 
-			// log.Println("out", err, outputBuf.String())
-			// Update the command's output and status
+		if !pool.TryAcquire() {
 			mu.Lock()
-			cmd.Status = status
+			cmd.Queued = true
+			title := paneTitle(cmd)
+			mu.Unlock()
+			proc.QueueDraw(ctx, app, func() {
+				output.SetTitle(title)
+			})
 
+			err := pool.Acquire(ctx, cmd.Priority)
+
+			mu.Lock()
+			cmd.Queued = false
+			title = paneTitle(cmd)
+			mu.Unlock()
 			if err != nil {
-				cmd.Status = err.Error()
-			} else {
-				cmd.Output = outputBuf.String()
+				return
 			}
-			content := fmt.Sprintf("Command: %s\nStatus: %s\nOutput:\n%s", cmd.Command, cmd.Status, cmd.Output)
+			proc.QueueDraw(ctx, app, func() {
+				output.SetTitle(title)
+			})
+		}
+
+		runCtx, cancelRun := ctx, context.CancelFunc(func() {})
+		if cmd.Timeout > 0 {
+			runCtx, cancelRun = context.WithTimeout(ctx, cmd.Timeout)
+		}
+		mu.Lock()
+		startedAt := startRun(cmd)
+		mu.Unlock()
+
+		var err error
+		if cmd.PTY {
+			err = runInTerminal(runCtx, execCmd, cmd.Term, &newlineWriter{w: &stdoutBuf})
+		} else {
+			execCmd.Stdout = &stdoutBuf
+			execCmd.Stderr = &stderrBuf
+			err = proc.Run(runCtx, execCmd)
+		}
+		cancelRun()
+		pool.Release()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil && attempt < attempts {
+			// Keep the previous outcome on screen until retries run out
+			delay := cmd.Retry.backoff(attempt)
+			mu.Lock()
+			cmd.IsRunning = false
+			cmd.Retrying = fmt.Sprintf("retry %d/%d in %s", attempt+1, attempts, delay)
+			title := paneTitle(cmd)
 			mu.Unlock()
+			log.Printf("attempt %d/%d of %q failed: %v\n", attempt, attempts, cmd.Command, err)
 
-			// Refresh the TextView on the UI thread
-			app.QueueUpdateDraw(func() {
-				output.SetText(content)
+			proc.QueueDraw(ctx, app, func() {
+				output.SetTitle(title)
 			})
 
-			// Sleep if the job is repeating
-			if cmd.Repeat > 0 {
-				time.Sleep(time.Duration(cmd.Repeat) * time.Second)
-			} else {
+			select {
+			case <-ctx.Done():
 				return
+			case <-time.After(delay):
+			}
+			continue
+		}
+
+		// log.Println("out", err, stdoutBuf.String())
+		// Update the command's output and status. A failed run keeps the
+		// last good output on screen next to its own stderr
+		mu.Lock()
+		recordRun(cmd, startedAt, err)
+		cmd.Stderr = stderrBuf.String()
+		if err == nil {
+			previous := cmd.Output
+			cmd.Output = stdoutBuf.String()
+			if cmd.Highlight {
+				if cmd.ChangedAt.IsZero() {
+					// Nothing to compare the first output with
+					previous = cmd.Output
+				}
+				if cmd.ChangedAt.IsZero() || cmd.Output != previous {
+					cmd.ChangedAt = cmd.FinishedAt
+				}
+				cmd.Changes = highlightChanges(stripANSI(previous), stripANSI(cmd.Output))
 			}
 		}
+		cmd.History.Add(RunRecord{
+			Run:        cmd.Runs,
+			Status:     cmd.Status,
+			ExitCode:   cmd.ExitCode,
+			StartedAt:  cmd.StartedAt,
+			FinishedAt: cmd.FinishedAt,
+			Duration:   cmd.Duration,
+			Output:     stdoutBuf.String(),
+			Stderr:     cmd.Stderr,
+		})
+		if cmd.Viewing > 0 {
+			// Stay on the run being looked at, as far back as history goes
+			cmd.Viewing = min(cmd.Viewing+1, cmd.History.Len()-1)
+		}
+		cmd.Retrying = ""
+		content := formatContent(cmd)
+		title := paneTitle(cmd)
+		mu.Unlock()
+
+		// Refresh the TextView on the UI thread
+		proc.QueueDraw(ctx, app, func() {
+			output.SetText(content)
+			output.SetTitle(title)
+		})
+		return
 	}
 }
 
+// reportRunKilled shows that a run was killed from the keyboard, keeping the
+// output of the last finished run on screen
+func reportRunKilled(ctx context.Context, cmd *Command, output *tview.TextView, mu *sync.Mutex, app *tview.Application) {
+	mu.Lock()
+	recordKill(cmd)
+	content := formatContent(cmd)
+	title := paneTitle(cmd)
+	mu.Unlock()
+	proc.QueueDraw(ctx, app, func() {
+		output.SetText(content)
+		output.SetTitle(title)
+	})
+}
+
+// reportKilled shows that the command was stopped for good
+func reportKilled(ctx context.Context, cmd *Command, output *tview.TextView, mu *sync.Mutex, app *tview.Application) {
+	mu.Lock()
+	recordKill(cmd)
+	cmd.Output = "Job terminated."
+	cmd.Changes = cmd.Output
+	cmd.Stderr = ""
+	content := formatContent(cmd)
+	mu.Unlock()
+	proc.QueueDraw(ctx, app, func() {
+		output.SetText(content)
+	})
+	log.Println("cancelling", cmd.Command)
+}
+
+// paneTitle renders the border title of the pane showing cmd. Callers must
+// hold the mutex guarding cmd
+func paneTitle(cmd *Command) string {
+	var title string
+	switch {
+	case cmd.Type == TypeTerminal:
+		title = fmt.Sprintf("Terminal: %s", cmd.Name)
+	case cmd.Schedule != nil:
+		title = fmt.Sprintf("Scheduled: %s", cmd.Name)
+	case cmd.Repeat > 0 || cmd.Mode == ModeStream:
+		title = fmt.Sprintf("Syncing: %s", cmd.Name)
+	default:
+		title = fmt.Sprintf("Command %s", cmd.Name)
+	}
+
+	if cmd.Highlight && !cmd.ChangedAt.IsZero() {
+		title += " · changed " + cmd.ChangedAt.Format("15:04:05")
+	}
+	if run, ok := cmd.History.Back(cmd.Viewing); ok && cmd.Viewing > 0 {
+		title += fmt.Sprintf(" · history: run #%d, %d/%d back", run.Run, cmd.Viewing, cmd.History.Len()-1)
+	}
+	if cmd.Paused {
+		title += " · paused"
+	}
+	if cmd.Queued {
+		title += " · queued"
+	}
+	if cmd.Retrying != "" {
+		title += " · " + cmd.Retrying
+	}
+	return title
+}
+
+// formatContent renders the pane text for cmd: a status header, the output
+// and stderr in red below it. When stepping through history, the run being
+// looked at is shown instead. Callers must hold the mutex guarding cmd
+func formatContent(cmd *Command) string {
+	header, output, stderr := formatHeader(cmd), cmd.Output, cmd.Stderr
+	live := cmd.Viewing == 0
+	if run, ok := cmd.History.Back(cmd.Viewing); ok && !live {
+		header, output, stderr = formatRunHeader(run), run.Output, run.Stderr
+	}
+
+	if cmd.Highlight && live {
+		output = cmd.Changes
+	} else {
+		output = renderOutput(output, cmd.ANSI)
+	}
+	content := fmt.Sprintf("%s\n\n%s", header, output)
+	if stderr != "" && !cmd.HideStderr {
+		content += fmt.Sprintf("\n[red]%s[-]", renderOutput(stderr, cmd.ANSI))
+	}
+	return content
+}
+
+// isRepeating reports whether cmd keeps its pane busy, as opposed to
+// commands run once
+func isRepeating(cmd *Command) bool {
+	return cmd.Repeat > 0 || cmd.Schedule != nil || cmd.Mode == ModeStream || cmd.Type == TypeTerminal
+}
+
 // GroupCommands groups commands into logical groups
 func GroupCommands(commands []*Command) []*Group {
 	var groups []*Group
 	var repeating []*Command
 	var nonRepeating []*Command
+	var matrices []*Group
 
-	// Separate repeating and non-repeating commands
+	// Separate repeating and non-repeating commands, the panes of each
+	// matrix go to a group of their own
+	byMatrix := make(map[*Matrix]*Group)
 	for _, cmd := range commands {
-		if cmd.Repeat > 0 {
+		if cmd.Matrix != nil {
+			group, ok := byMatrix[cmd.Matrix]
+			if !ok {
+				group = &Group{}
+				byMatrix[cmd.Matrix] = group
+				matrices = append(matrices, group)
+			}
+			group.Matrix = append(group.Matrix, cmd)
+		} else if isRepeating(cmd) {
 			repeating = append(repeating, cmd)
 		} else {
 			nonRepeating = append(nonRepeating, cmd)
@@ -148,15 +546,19 @@ func GroupCommands(commands []*Command) []*Group {
 		groups = append(groups, group)
 	}
 
-	return groups
+	return append(groups, matrices...)
 }
 
 func CreateGroupedFlex(state *AppState) []*tview.Flex {
+	if state.Layout != nil {
+		return []*tview.Flex{createLayoutFlex(state)}
+	}
+
 	groups := []*tview.Flex{}
 
 	for groupIndex, group := range state.Groups {
-		log.Printf("Creating group %d with %d repeating and %d non-repeating commands\n",
-			groupIndex, len(group.Repeating), len(group.NonRepeating))
+		log.Printf("Creating group %d with %d repeating, %d non-repeating and %d matrix commands\n",
+			groupIndex, len(group.Repeating), len(group.NonRepeating), len(group.Matrix))
 
 		// Group-level flex container (vertical stacking)
 		groupFlex := tview.NewFlex().SetDirection(tview.FlexRow)
@@ -166,32 +568,14 @@ func CreateGroupedFlex(state *AppState) []*tview.Flex {
 
 		// Add repeating commands (each in its own row)
 		for _, cmd := range group.Repeating {
-			textView := tview.NewTextView().
-				SetDynamicColors(true).
-				SetScrollable(true)
-
-			textView.SetBorder(true)
-			textView.SetTitle(fmt.Sprintf("Syncing: %s", cmd.Name))
-			textView.SetBorderColor(tcell.ColorGreen)
-
-			state.TextViews[groupIndex] = append(state.TextViews[groupIndex], textView)
-			groupFlex.AddItem(textView, 0, 1, false) // Each repeating command gets a row
+			groupFlex.AddItem(createPane(state, groupIndex, cmd), 0, 1, false) // Each repeating command gets a row
 		}
 
 		// Add non-repeating commands (2 per row)
 		if len(group.NonRepeating) > 0 {
 			nonRepeatingFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
 			for i, cmd := range group.NonRepeating {
-				textView := tview.NewTextView().
-					SetDynamicColors(true).
-					SetScrollable(true)
-
-				textView.SetBorder(true)
-				textView.SetTitle(fmt.Sprintf("Command %s", cmd.Name))
-				textView.SetBorderColor(tcell.ColorBlue)
-
-				state.TextViews[groupIndex] = append(state.TextViews[groupIndex], textView)
-				nonRepeatingFlex.AddItem(textView, 0, 1, false)
+				nonRepeatingFlex.AddItem(createPane(state, groupIndex, cmd), 0, 1, false)
 
 				// Every 2 commands, finalize the row and start a new one
 				if (i+1)%2 == 0 || i == len(group.NonRepeating)-1 {
@@ -201,6 +585,15 @@ func CreateGroupedFlex(state *AppState) []*tview.Flex {
 			}
 		}
 
+		// Add the panes of a matrix in a grid
+		if len(group.Matrix) > 0 {
+			var panes []tview.Primitive
+			for _, cmd := range group.Matrix {
+				panes = append(panes, createPane(state, groupIndex, cmd))
+			}
+			groupFlex.AddItem(createGrid(&GridLayout{}, panes), 0, 1, false)
+		}
+
 		// Add the group to the main layout
 		groups = append(groups, groupFlex)
 	}
@@ -208,6 +601,172 @@ func CreateGroupedFlex(state *AppState) []*tview.Flex {
 	return groups
 }
 
+// createPane creates the pane showing cmd as the next pane of the group at
+// groupIndex. Repeating commands get a green border, the others a blue one
+func createPane(state *AppState, groupIndex int, cmd *Command) tview.Primitive {
+	paneIndex := len(state.TextViews[groupIndex])
+
+	// A command kept across a reload keeps its pane, which its runner draws to
+	if kept, ok := state.Kept[cmd]; ok {
+		if terminal, ok := kept.view.(*terminalPane); ok {
+			state.Terminals[[2]int{groupIndex, paneIndex}] = terminal
+			state.TextViews[groupIndex] = append(state.TextViews[groupIndex], nil)
+		} else {
+			state.TextViews[groupIndex] = append(state.TextViews[groupIndex], kept.view.(*tview.TextView))
+		}
+		return kept.view
+	}
+	borderColor := tcell.ColorBlue
+	if isRepeating(cmd) {
+		borderColor = tcell.ColorGreen
+	}
+
+	if cmd.Type == TypeTerminal {
+		terminal := newTerminalPane()
+		terminal.SetBorder(true)
+		terminal.SetTitle(paneTitle(cmd))
+		terminal.SetBorderColor(borderColor)
+
+		state.Terminals[[2]int{groupIndex, paneIndex}] = terminal
+		state.TextViews[groupIndex] = append(state.TextViews[groupIndex], nil)
+		return terminal
+	}
+
+	textView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true)
+
+	textView.SetBorder(true)
+	textView.SetTitle(paneTitle(cmd))
+	textView.SetBorderColor(borderColor)
+	if cmd.Mode == ModeStream {
+		textView.SetMaxLines(cmd.Scrollback)
+	}
+	if cmd.PTY {
+		cmd.Term.track(textView)
+	}
+
+	state.TextViews[groupIndex] = append(state.TextViews[groupIndex], textView)
+	return textView
+}
+
+// newAppState groups the commands of a page, an explicit layout puts them
+// all in one group
+func newAppState(commands []*Command, settings *PageSettings) *AppState {
+	groups := GroupCommands(commands)
+	if settings.Layout != nil {
+		groups = []*Group{LayoutGroup(commands, settings.Layout)}
+	}
+
+	return &AppState{
+		Groups:      groups,
+		TextViews:   make([][]*tview.TextView, len(groups)),
+		Terminals:   make(map[[2]int]*terminalPane),
+		Layout:      settings.Layout,
+		CancelFuncs: make(map[[2]int]context.CancelFunc),
+		Actions:     make(map[[2]int]chan paneAction),
+	}
+}
+
+// paneViews returns every pane of the page, in the order Tab goes through them
+func (state *AppState) paneViews() []paneView {
+	var views []paneView
+	for groupIndex, row := range state.TextViews {
+		for paneIndex, textView := range row {
+			if terminal := state.Terminals[[2]int{groupIndex, paneIndex}]; terminal != nil {
+				views = append(views, terminal)
+			} else {
+				views = append(views, textView)
+			}
+		}
+	}
+	return views
+}
+
+// filePage is what is on screen for a commands file
+type filePage struct {
+	state  *AppState
+	flex   *tview.Flex
+	banner *tview.TextView // Why the file couldn't be reloaded, hidden until then
+}
+
+// createPage creates the panes of state under a title naming the file
+func createPage(state *AppState, fileIndex int, filePath string) *filePage {
+	// Create grouped layout for this file
+	groupItems := CreateGroupedFlex(state)
+
+	pageTitle := tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignCenter).
+		SetText(fmt.Sprintf("[::b]Page %d: %s[-:-:-]", fileIndex+1, filePath))
+
+	pageTitle.SetBorder(true)
+	pageTitle.SetBorderColor(tcell.ColorYellow)
+
+	banner := tview.NewTextView().SetDynamicColors(true)
+	banner.SetBorder(true)
+	banner.SetBorderColor(tcell.ColorRed)
+	banner.SetTitle("Reload failed, still running the previous version")
+
+	page := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(pageTitle, 3, 1, false).
+		AddItem(banner, 0, 0, false)
+
+	for _, group := range groupItems {
+		page.AddItem(group, 0, 1, false)
+	}
+	return &filePage{state: state, flex: page, banner: banner}
+}
+
+// showError shows err in the banner at the top of the page
+func (p *filePage) showError(err error) {
+	text := err.Error()
+	lines := min(strings.Count(text, "\n")+1, maxBannerLines)
+	p.banner.SetText("[red]" + tview.Escape(text) + "[-]")
+	p.flex.ResizeItem(p.banner, lines+2, 0)
+}
+
+// startPanes starts a runner for each pane of state and returns where the
+// keyboard actions of each go, in the order of state.paneViews. Panes kept
+// across a reload already have a runner
+func startPanes(ctx context.Context, state *AppState, app *tview.Application, pool *workerPool, wg *sync.WaitGroup) []chan paneAction {
+	var pageActions []chan paneAction
+	for groupIndex, group := range state.Groups {
+		for paneIndex, cmd := range group.Commands() {
+			key := [2]int{groupIndex, paneIndex}
+			if kept, ok := state.Kept[cmd]; ok {
+				state.CancelFuncs[key] = kept.cancel
+				state.Actions[key] = kept.actions
+				pageActions = append(pageActions, kept.actions)
+				continue
+			}
+
+			wg.Add(1)
+
+			childCtx, childCancel := context.WithCancel(ctx)
+			state.CancelFuncs[key] = childCancel
+
+			actions := make(chan paneAction, 1)
+			state.Actions[key] = actions
+			pageActions = append(pageActions, actions)
+
+			go func(cx context.Context, cmd *Command, groupIndex, paneIndex int) {
+				defer wg.Done()
+				if cmd.Type == TypeTerminal {
+					RunTerminal(cx, cmd, state.Terminals[[2]int{groupIndex, paneIndex}], &state.Mu, app, actions)
+					return
+				}
+				if cmd.Mode == ModeStream {
+					StreamCommand(cx, cmd, state.TextViews[groupIndex][paneIndex], &state.Mu, app, actions)
+					return
+				}
+				ExecuteCommand(cx, cmd, state.TextViews[groupIndex][paneIndex], &state.Mu, app, pool, actions)
+			}(childCtx, cmd, groupIndex, paneIndex)
+		}
+	}
+	return pageActions
+}
+
 // CreateApp initializes the TUI application
 func CreateApp(state *AppState, groups []*tview.Flex, cancel context.CancelFunc) *tview.Application {
 	app := tview.NewApplication()
@@ -233,39 +792,237 @@ func CreateApp(state *AppState, groups []*tview.Flex, cancel context.CancelFunc)
 }
 
 type YAMLCommand struct {
-	Name    string `yaml:"name"`
-	Command string `yaml:"command"`
-	Repeat  int    `yaml:"repeat"`
+	Use      string      `yaml:"use"` // snippet the command starts from
+	ID       string      `yaml:"id"`  // referenced by the layout
+	Name     string      `yaml:"name"`
+	Matrix   *Matrix     `yaml:"matrix"`   // one pane per combination of values, e.g. host: [a, b]
+	Command  CommandLine `yaml:"command"`  // a shell string, or a list run without a shell
+	Repeat   Duration    `yaml:"repeat"`   // e.g. 5 (seconds), "500ms", "15m"
+	Schedule string      `yaml:"schedule"` // cron expression, e.g. "*/5 * * * *" or "@hourly"
+	Timeout  Duration    `yaml:"timeout"`  // e.g. "30s", "2m"
+	Overlap  string      `yaml:"overlap"`  // skip, queue or kill-previous
+	Jitter   Duration    `yaml:"jitter"`
+
+	Type       string `yaml:"type"`       // "terminal" for interactive programs like htop or psql
+	Mode       string `yaml:"mode"`       // "stream" for long-running commands like `tail -f`
+	Scrollback int    `yaml:"scrollback"` // lines kept by a streaming pane
+	Restart    string `yaml:"restart"`    // never, on-failure or always
+	HideStderr bool   `yaml:"hide_stderr"`
+	History    int    `yaml:"history"` // finished runs kept for stepping back through
+
+	HighlightChanges bool `yaml:"highlight_changes"` // mark what changed since the previous run, like watch -d
+	ANSI             bool `yaml:"ansi"`              // render ANSI colors, e.g. from ls --color=always
+	PTY              bool `yaml:"pty"`               // run under a terminal sized after the pane, implies ansi
+
+	Retry    *YAMLRetry `yaml:"retry"`
+	Priority int        `yaml:"priority"` // higher runs first when -max-parallel is reached
+
+	Env   map[string]string `yaml:"env"`
+	Cwd   string            `yaml:"cwd"`   // relative to the yaml file
+	Shell string            `yaml:"shell"` // e.g. "bash -lc", defaults to "sh -c"
 }
 
 type YAMLConfig struct {
-	Commands []YAMLCommand `yaml:"commands"`
+	MaxParallel int                  `yaml:"max_parallel"` // commands running at once, 0 for no limit
+	Include     []string             `yaml:"include"`      // files whose commands come first, relative to this one
+	Vars        map[string]string    `yaml:"vars"`         // defaults of the {{.name}} vars used in commands
+	Defaults    YAMLDefaults         `yaml:"defaults"`     // env, cwd and shell inherited by the file's commands
+	Snippets    map[string]yaml.Node `yaml:"snippets"`     // reusable command settings, picked with `use:`
+	Commands    []yaml.Node          `yaml:"commands"`     // decoded one by one, see loadConfigFile
+	Layout      *LayoutNode          `yaml:"layout"`       // where each pane goes, by command id
+}
+
+// PageSettings holds the file-level settings of a commands file
+type PageSettings struct {
+	MaxParallel int
+	Layout      *LayoutNode // nil to group panes automatically
+	Files       []string    // Absolute paths of the file and every file it includes
 }
 
 // LoadCommandsFromYAML parses the YAML file and returns a list of commands
-func LoadCommandsFromYAML(filename string) ([]*Command, error) {
-	file, err := os.Open(filename)
+// along with the file-level settings. The settings also come with problems
+// in the file, for the files that were read
+func LoadCommandsFromYAML(filename string, overrides map[string]string) ([]*Command, *PageSettings, error) {
+	errs := newConfigErrors(filename)
+	loaded := make(map[string]*configFile)
+	page, err := loadConfigFile(filename, nil, loaded, errs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open YAML file: %v", err)
+		return nil, nil, err
 	}
-	defer file.Close()
+	config, doc := page.config, page.doc
 
-	var config YAMLConfig
-	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&config); err != nil {
-		return nil, fmt.Errorf("failed to decode YAML file: %v", err)
+	if config.MaxParallel < 0 {
+		errs.add(fieldNode(doc, "max_parallel"), "max_parallel must not be negative, got %d", config.MaxParallel)
+	}
+	if len(page.commands) == 0 && len(errs.errs) == 0 {
+		errs.add(fieldNode(doc, "commands"), "no commands")
 	}
+	settings := &PageSettings{
+		MaxParallel: config.MaxParallel,
+		Layout:      config.Layout,
+		Files:       slices.Sorted(maps.Keys(loaded)),
+	}
+
+	names := make(map[string]*yaml.Node)
+	ids := make(map[string]*yaml.Node)
 
 	var commands []*Command
-	for _, yamlCmd := range config.Commands {
+	vars := resolveVars(page.vars, overrides)
+	for _, expanded := range expandCommands(page.commands, vars, errs) {
+		yamlCmd, node := expanded.yamlCmd, expanded.source.node
+
+		if yamlCmd.Name == "" {
+			errs.add(node, "command without a name")
+		} else if first, ok := names[yamlCmd.Name]; ok {
+			errs.add(fieldNode(node, "name"), "name %q is already used on line %d", yamlCmd.Name, first.Line)
+		} else {
+			names[yamlCmd.Name] = fieldNode(node, "name")
+		}
+		if yamlCmd.ID == "" && config.Layout != nil && config.Layout.Grid == nil {
+			errs.add(node, "command %q needs an id to be placed in the layout", yamlCmd.Name)
+		}
+		// The panes of a matrix share its id
+		if yamlCmd.ID != "" {
+			if first, ok := ids[yamlCmd.ID]; ok && first != fieldNode(node, "id") {
+				errs.add(fieldNode(node, "id"), "id %q is already used on line %d", yamlCmd.ID, first.Line)
+			} else {
+				ids[yamlCmd.ID] = fieldNode(node, "id")
+			}
+		}
+		if strings.TrimSpace(yamlCmd.Command.Script) == "" && len(yamlCmd.Command.Argv) == 0 {
+			errs.add(fieldNode(node, "command"), "command %q has nothing to run", yamlCmd.Name)
+		}
+		if yamlCmd.Scrollback < 0 {
+			errs.add(fieldNode(node, "scrollback"), "scrollback must not be negative, got %d", yamlCmd.Scrollback)
+		}
+		if yamlCmd.History < 0 {
+			errs.add(fieldNode(node, "history"), "history must not be negative, got %d", yamlCmd.History)
+		}
+
+		switch yamlCmd.Mode {
+		case "":
+		case ModeStream:
+			// A stream runs once for as long as it lasts, and shows no past runs
+			for _, field := range []string{"repeat", "schedule", "timeout", "overlap", "retry", "history", "highlight_changes"} {
+				if value := mappingValue(node, field); value != nil {
+					errs.add(value, "a stream can't have %s", field)
+				}
+			}
+		default:
+			errs.add(fieldNode(node, "mode"), "unknown mode %q, expected stream", yamlCmd.Mode)
+		}
+
+		switch yamlCmd.Type {
+		case "":
+		case TypeTerminal:
+			if yamlCmd.Repeat > 0 || yamlCmd.Schedule != "" || yamlCmd.Mode != "" {
+				errs.add(fieldNode(node, "type"), "a terminal can't have repeat, schedule or mode")
+			}
+		default:
+			errs.add(fieldNode(node, "type"), "unknown type %q, expected terminal", yamlCmd.Type)
+		}
+
+		var schedule cron.Schedule
+		if yamlCmd.Schedule != "" {
+			if yamlCmd.Repeat > 0 {
+				errs.add(fieldNode(node, "schedule"), "set either repeat or schedule, not both")
+			}
+
+			schedule, err = cron.ParseStandard(yamlCmd.Schedule)
+			if err != nil {
+				errs.add(fieldNode(node, "schedule"), "invalid schedule %q: %v", yamlCmd.Schedule, err)
+			} else if schedule.Next(time.Now()).IsZero() {
+				errs.add(fieldNode(node, "schedule"), "schedule %q never comes up", yamlCmd.Schedule)
+			}
+		}
+
+		var retry *RetryPolicy
+		if yamlCmd.Retry != nil {
+			retry, err = newRetryPolicy(yamlCmd.Retry)
+			if err != nil {
+				errs.add(fieldNode(node, "retry"), "%v", err)
+			}
+		}
+
+		overlap := yamlCmd.Overlap
+		switch overlap {
+		case "":
+			overlap = OverlapSkip
+		case OverlapSkip, OverlapQueue, OverlapKillPrevious:
+		default:
+			errs.add(fieldNode(node, "overlap"), "unknown overlap policy %q, expected skip, queue or kill-previous", yamlCmd.Overlap)
+		}
+
+		restart := yamlCmd.Restart
+		switch restart {
+		case "":
+			restart = RestartNever
+		case RestartNever, RestartOnFailure, RestartAlways:
+		default:
+			errs.add(fieldNode(node, "restart"), "unknown restart policy %q, expected never, on-failure or always", yamlCmd.Restart)
+		}
+
+		command := yamlCmd.Command.Script
+		if argv := yamlCmd.Command.Argv; len(argv) > 0 {
+			if yamlCmd.Shell != "" {
+				errs.add(fieldNode(node, "shell"), "shell has no effect on a command given as a list")
+			}
+			command = strings.Join(argv, " ")
+		}
+
+		history := yamlCmd.History
+		if history <= 0 {
+			history = defaultHistory
+		}
+
+		var term *termSize
+		if yamlCmd.PTY {
+			term = newTermSize()
+		}
+
+		scrollback := yamlCmd.Scrollback
+		if scrollback <= 0 {
+			scrollback = defaultScrollback
+		}
+
 		commands = append(commands, &Command{
-			Name:    yamlCmd.Name,
-			Command: yamlCmd.Command,
-			Repeat:  yamlCmd.Repeat,
+			ID:         yamlCmd.ID,
+			Name:       yamlCmd.Name,
+			Command:    command,
+			Argv:       yamlCmd.Command.Argv,
+			Shell:      resolveShell(expanded.defaults, yamlCmd),
+			Env:        resolveEnv(expanded.defaults, yamlCmd),
+			Cwd:        resolveCwd(expanded.defaults, yamlCmd, filepath.Dir(expanded.source.file)),
+			Repeat:     time.Duration(yamlCmd.Repeat),
+			Schedule:   schedule,
+			Timeout:    time.Duration(yamlCmd.Timeout),
+			Overlap:    overlap,
+			Jitter:     time.Duration(yamlCmd.Jitter),
+			Type:       yamlCmd.Type,
+			Mode:       yamlCmd.Mode,
+			Scrollback: scrollback,
+			Restart:    restart,
+			HideStderr: yamlCmd.HideStderr,
+			Highlight:  yamlCmd.HighlightChanges,
+			ANSI:       yamlCmd.ANSI || yamlCmd.PTY,
+			PTY:        yamlCmd.PTY,
+			Retry:      retry,
+			Priority:   yamlCmd.Priority,
+			History:    newRunHistory(history),
+			Term:       term,
+			Matrix:     yamlCmd.Matrix,
+			Spec:       commandSpec{yamlCmd: yamlCmd, defaults: expanded.defaults, file: expanded.source.file},
 		})
 	}
 
-	return commands, nil
+	if config.Layout != nil {
+		validateLayout(config.Layout, ids, errs)
+	}
+	if err := errs.err(); err != nil {
+		return nil, settings, err
+	}
+
+	return commands, settings, nil
 }
 
 type paginator struct {
@@ -299,15 +1056,26 @@ func (p *paginator) prev() int32 {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(validate(os.Args[2:]))
+	}
+
 	var filePaths string
+	var maxParallel int
 
 	// Accept comma-separated YAML file paths
 	flag.StringVar(&filePaths, "cfg", "", "provide comma-separated commands config yaml files")
+	vars := make(varFlags)
+	flag.Var(vars, "var", "set a var used in the commands files, as key=value, can be repeated")
+	flag.IntVar(&maxParallel, "max-parallel", 0, "max commands running at once across all pages, overrides max_parallel in the yaml files (0 = no limit)")
 	flag.Parse()
 
 	if filePaths == "" {
 		log.Fatal("no commands files provided")
 	}
+	if maxParallel < 0 {
+		log.Fatalf("-max-parallel must not be negative, got %d", maxParallel)
+	}
 
 	// Parse files
 	files := strings.Split(filePaths, ",")
@@ -322,72 +1090,61 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	var wg sync.WaitGroup
 
+	// Panes launched below queue up until every file is loaded and the limit is known
+	pool := newWorkerPool()
+	limit := maxParallel
+
 	cursor := newPaginator(int32(len(files)))
 
-	// pageTextViews[pageIndex] = flat list of all TextViews on that page, for focus cycling
-	pageTextViews := make(map[int][]*tview.TextView)
+	// pageTextViews[pageIndex] = flat list of all panes on that page, for focus cycling
+	pageTextViews := make(map[int][]paneView)
 	focusedPane := make(map[int]int) // pageIndex -> currently focused pane index
+	// pageActions[pageIndex] = keyboard action channels, in the same order as pageTextViews
+	pageActions := make(map[int][]chan paneAction)
+	// filePages[pageIndex] = what is on screen for the file, replaced when it is reloaded
+	filePages := make(map[int]*filePage)
+	// pageFiles[pageIndex] = the file and the files it includes, watched for changes
+	pageFiles := make([][]string, len(files))
 
 	// Process each file
 	for fileIndex, filePath := range files {
 		// Load commands from YAML
-		commands, err := LoadCommandsFromYAML(filePath)
+		commands, settings, err := LoadCommandsFromYAML(filePath, vars)
 		if err != nil {
-			log.Fatalf("failed to decode yaml from file %s. error: %v", filePath, err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 
-		// Group commands
-		groups := GroupCommands(commands)
-
-		// Initialize state for this file
-		state := &AppState{
-			Groups:      groups,
-			TextViews:   make([][]*tview.TextView, len(groups)),
-			CancelFuncs: make(map[[2]int]context.CancelFunc),
+		// Without the flag, the strictest max_parallel across files wins
+		if maxParallel == 0 && settings.MaxParallel > 0 && (limit == 0 || settings.MaxParallel < limit) {
+			limit = settings.MaxParallel
 		}
 
-		// Create grouped layout for this file
-		groupItems := CreateGroupedFlex(state)
+		pageFiles[fileIndex] = settings.Files
 
-		pageTitle := tview.NewTextView().
-			SetDynamicColors(true).
-			SetTextAlign(tview.AlignCenter).
-			SetText(fmt.Sprintf("[::b]Page %d: %s[-:-:-]", fileIndex+1, filePath))
-
-		pageTitle.SetBorder(true)
-		pageTitle.SetBorderColor(tcell.ColorYellow)
-
-		// Add a new page for this file
-		page := tview.NewFlex().SetDirection(tview.FlexRow).
-			AddItem(pageTitle, 3, 1, false)
-
-		for _, group := range groupItems {
-			page.AddItem(group, 0, 1, false)
-		}
-		pages.AddPage(fmt.Sprintf("file-%d", fileIndex), page, true, fileIndex == 0)
+		state := newAppState(commands, settings)
+		page := createPage(state, fileIndex, filePath)
+		pages.AddPage(fmt.Sprintf("file-%d", fileIndex), page.flex, true, fileIndex == 0)
+		filePages[fileIndex] = page
 
-		// Flatten all TextViews for this page so we can Tab through them
-		var flatViews []*tview.TextView
-		for _, row := range state.TextViews {
-			flatViews = append(flatViews, row...)
-		}
-		pageTextViews[fileIndex] = flatViews
+		// Flatten all panes for this page so we can Tab through them
+		pageTextViews[fileIndex] = state.paneViews()
 		focusedPane[fileIndex] = -1 // no pane focused initially
 
 		// Execute commands for this file
-		for groupIndex, group := range groups {
-			for paneIndex, cmd := range append(group.Repeating, group.NonRepeating...) {
-				wg.Add(1)
+		pageActions[fileIndex] = startPanes(ctx, state, app, pool, &wg)
+	}
 
-				childCtx, childCancel := context.WithCancel(ctx)
-				state.CancelFuncs[[2]int{groupIndex, paneIndex}] = childCancel
+	// Hand out slots once the event loop is up, by then the panes launched
+	// above are all waiting in line and the highest priorities go first
+	go app.QueueUpdate(func() {
+		pool.Open(limit)
+	})
 
-				go func(cx context.Context, cmd *Command, groupIndex, paneIndex int) {
-					defer wg.Done()
-					ExecuteCommand(cx, cmd, state.TextViews[groupIndex][paneIndex], &state.Mu, app)
-				}(childCtx, cmd, groupIndex, paneIndex)
-			}
-		}
+	// Anything logged to the terminal from here on would garble the TUI,
+	// run with DEBUG=1 to get the logs in app.log instead
+	if os.Getenv("DEBUG") != "1" {
+		log.SetOutput(io.Discard)
 	}
 
 	setFocusedPane := func(pageIdx, paneIdx int) {
@@ -406,10 +1163,47 @@ func main() {
 		app.SetFocus(tv)
 	}
 
+	// focusedActions returns where keyboard actions for the focused pane go,
+	// nil when no pane on the page is focused
+	focusedActions := func(pageIdx int) chan paneAction {
+		paneIdx := focusedPane[pageIdx]
+		if paneIdx < 0 || paneIdx >= len(pageActions[pageIdx]) {
+			return nil
+		}
+		return pageActions[pageIdx][paneIdx]
+	}
+
+	// tview stops on Ctrl-C before the pages see it. A focused terminal pane
+	// gets it instead, as a new event which tview passes on
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if _, ok := app.GetFocus().(*terminalPane); ok && event.Key() == tcell.KeyCtrlC {
+			return tcell.NewEventKey(tcell.KeyCtrlC, 0, event.Modifiers())
+		}
+		return event
+	})
+
 	// Set up navigation between pages
 	pages.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		pageIdx := int(cursor.current)
+
+		// A focused terminal pane gets every key but the one releasing it
+		if _, ok := app.GetFocus().(*terminalPane); ok {
+			if event.Key() == terminalReleaseKey {
+				app.SetFocus(pages)
+				return nil
+			}
+			return event
+		}
+
 		switch event.Key() {
+		case tcell.KeyEnter: // Give the keyboard back to a released terminal pane
+			tvs := pageTextViews[pageIdx]
+			if paneIdx := focusedPane[pageIdx]; paneIdx >= 0 && paneIdx < len(tvs) {
+				if terminal, ok := tvs[paneIdx].(*terminalPane); ok {
+					app.SetFocus(terminal)
+					return nil
+				}
+			}
 		case tcell.KeyTab:
 			tvs := pageTextViews[pageIdx]
 			if len(tvs) > 0 {
@@ -438,10 +1232,88 @@ func main() {
 			page := cursor.prev()
 			pages.SwitchToPage(fmt.Sprintf("file-%d", page))
 			app.SetFocus(pages)
+		case 'r': // Rerun the focused pane
+			sendAction(focusedActions(pageIdx), actionRerun)
+			return nil
+		case 'R': // Rerun every pane on the page
+			// Terminals are left alone, restarting them would end their sessions
+			for paneIdx, actions := range pageActions[pageIdx] {
+				if _, ok := pageTextViews[pageIdx][paneIdx].(*terminalPane); ok {
+					continue
+				}
+				sendAction(actions, actionRerun)
+			}
+			return nil
+		case ' ': // Pause or resume the focused pane
+			sendAction(focusedActions(pageIdx), actionTogglePause)
+			return nil
+		case 'x': // Kill the focused pane's run in flight
+			sendAction(focusedActions(pageIdx), actionKillRun)
+			return nil
+		case '[': // Step back through the focused pane's past runs
+			sendAction(focusedActions(pageIdx), actionHistoryBack)
+			return nil
+		case ']': // Step forward again, towards the latest run
+			sendAction(focusedActions(pageIdx), actionHistoryForward)
+			return nil
 		}
 		return event
 	})
 
+	// reloadPage loads the file of the page at pageIdx again and swaps the
+	// page for the new one. Panes whose command is unchanged keep running.
+	// It returns the files the page now comes from
+	reloadPage := func(pageIdx int) []string {
+		commands, settings, err := LoadCommandsFromYAML(files[pageIdx], vars)
+		proc.QueueDraw(ctx, app, func() {
+			if ctx.Err() != nil {
+				return
+			}
+			old := filePages[pageIdx]
+			if err != nil {
+				log.Printf("Reloading %s: %v\n", files[pageIdx], err)
+				old.showError(err)
+				return
+			}
+
+			kept := reusePanes(old.state, commands)
+			state := newAppState(commands, settings)
+			state.Kept = kept
+			page := createPage(state, pageIdx, files[pageIdx])
+
+			focused := app.GetFocus()
+			pages.AddPage(fmt.Sprintf("file-%d", pageIdx), page.flex, true, pageIdx == int(cursor.current))
+			filePages[pageIdx] = page
+
+			// Keep the focus on the pane it was on, if that is still there
+			var focusedView paneView
+			if paneIdx := focusedPane[pageIdx]; paneIdx >= 0 {
+				focusedView = pageTextViews[pageIdx][paneIdx]
+			}
+			pageTextViews[pageIdx] = state.paneViews()
+			focusedPane[pageIdx] = slices.Index(pageTextViews[pageIdx], focusedView)
+
+			// The focus may also have been on the old page itself
+			shown := false
+			for _, views := range pageTextViews {
+				shown = shown || slices.ContainsFunc(views, func(view paneView) bool { return view == focused })
+			}
+			if !shown {
+				focused = pages
+			}
+			app.SetFocus(focused)
+
+			pageActions[pageIdx] = startPanes(ctx, state, app, pool, &wg)
+		})
+
+		// A file that can't be read at all leaves the page as it was
+		if settings == nil {
+			return nil
+		}
+		return settings.Files
+	}
+	go watchFiles(ctx, pageFiles, reloadPage)
+
 	// Run the TUI
 	go func() {
 		if err := app.SetRoot(pages, true).Run(); err != nil {
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Matrix fans a single command out into one pane per combination of its
+// values, each value used in the command as a var
+//
+//	matrix:
+//	  worker: [1, 2, 3]
+//	command: "tail -f /var/log/worker-{{.worker}}.log"
+type Matrix struct {
+	Keys   []string   // var names, in the order they are written
+	Values [][]string // values of each key
+}
+
+// UnmarshalYAML keeps the keys in the order they are written, so that panes
+// come out in the order one would expect
+func (m *Matrix) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return nodeError(node, "matrix must map names to lists of values, e.g. host: [a, b]")
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		var values []string
+		if err := value.Decode(&values); err != nil || len(values) == 0 {
+			return nodeError(value, "matrix %s must be a non-empty list of values", key.Value)
+		}
+		m.Keys = append(m.Keys, key.Value)
+		m.Values = append(m.Values, values)
+	}
+	return nil
+}
+
+// combinations returns every combination of the values, the last key
+// changing fastest
+func (m *Matrix) combinations() [][]string {
+	combinations := [][]string{nil}
+	for _, values := range m.Values {
+		var next [][]string
+		for _, combination := range combinations {
+			for _, value := range values {
+				next = append(next, append(slices.Clone(combination), value))
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// expandedCommand is a command of a commands file with its vars expanded,
+// one of several when it has a matrix
+type expandedCommand struct {
+	source   sourceCommand
+	yamlCmd  YAMLCommand
+	defaults YAMLDefaults
+}
+
+// expandCommands decodes the commands and expands their vars and matrix
+func expandCommands(sources []sourceCommand, vars map[string]string, errs *configErrors) []expandedCommand {
+	var expanded []expandedCommand
+	fileDefaults := make(map[*yaml.Node]YAMLDefaults) // expanded once per file
+	for _, source := range sources {
+		// Problems decoding the command were reported while loading it
+		var yamlCmd YAMLCommand
+		_ = source.node.Decode(&yamlCmd)
+
+		defaults, ok := fileDefaults[source.defaults]
+		if !ok && source.defaults != nil {
+			_ = source.defaults.Decode(&defaults)
+			expandDefaultsVars(&defaults, source.defaults, vars, errs)
+			fileDefaults[source.defaults] = defaults
+		}
+
+		if yamlCmd.Matrix == nil {
+			expandCommandVars(&yamlCmd, source.node, vars, errs)
+			expanded = append(expanded, expandedCommand{source: source, yamlCmd: yamlCmd, defaults: defaults})
+			continue
+		}
+
+		var panes []expandedCommand
+		combinations := yamlCmd.Matrix.combinations()
+		for _, combination := range combinations {
+			paneVars := make(map[string]string, len(vars)+len(yamlCmd.Matrix.Keys))
+			maps.Copy(paneVars, vars)
+			for i, key := range yamlCmd.Matrix.Keys {
+				paneVars[key] = combination[i]
+			}
+
+			pane := yamlCmd
+			pane.Command.Argv = slices.Clone(yamlCmd.Command.Argv)
+			pane.Env = maps.Clone(yamlCmd.Env)
+			expandCommandVars(&pane, source.node, paneVars, errs)
+			panes = append(panes, expandedCommand{source: source, yamlCmd: pane, defaults: defaults})
+		}
+
+		// A name that doesn't tell the panes apart gets their values
+		// appended
+		names := make(map[string]bool, len(panes))
+		for _, pane := range panes {
+			names[pane.yamlCmd.Name] = true
+		}
+		if len(names) < len(panes) {
+			for i := range panes {
+				panes[i].yamlCmd.Name += " (" + strings.Join(combinations[i], ", ") + ")"
+			}
+		}
+		expanded = append(expanded, panes...)
+	}
+	return expanded
+}
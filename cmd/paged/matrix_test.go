@@ -0,0 +1,98 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMatrixCombinations(t *testing.T) {
+	var m Matrix
+	if err := yaml.Unmarshal([]byte("{host: [a, b], port: [80, 443]}"), &m); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(m.Keys, []string{"host", "port"}) {
+		t.Errorf("keys = %v, want them in the order they are written", m.Keys)
+	}
+
+	want := [][]string{{"a", "80"}, {"a", "443"}, {"b", "80"}, {"b", "443"}}
+	got := m.combinations()
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("combinations() = %v, want %v", got, want)
+	}
+}
+
+func TestMatrixUnmarshalErrors(t *testing.T) {
+	for _, text := range []string{"[a, b]", "{host: []}", "{host: {a: b}}"} {
+		var m Matrix
+		if err := yaml.Unmarshal([]byte(text), &m); err == nil {
+			t.Errorf("unmarshaling %s: no error", text)
+		}
+	}
+}
+
+func TestLoadCommandsMatrixNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix string
+		want   []string
+	}{
+		{
+			name:   "name uses every key",
+			matrix: `name: "w {{.a}}{{.b}}"`,
+			want:   []string{"w 1x", "w 1y", "w 2x", "w 2y"},
+		},
+		{
+			name:   "name uses the last key only",
+			matrix: `name: "w {{.b}}"`,
+			want:   []string{"w x (1, x)", "w y (1, y)", "w x (2, x)", "w y (2, y)"},
+		},
+		{
+			name:   "name uses the first key only",
+			matrix: `name: "w {{.a}}"`,
+			want:   []string{"w 1 (1, x)", "w 1 (1, y)", "w 2 (2, x)", "w 2 (2, y)"},
+		},
+		{
+			name:   "name uses no key",
+			matrix: `name: "w"`,
+			want:   []string{"w (1, x)", "w (1, y)", "w (2, x)", "w (2, y)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeConfig(t, "a.yaml", `
+commands:
+  - `+tt.matrix+`
+    matrix: {a: [1, 2], b: [x, y]}
+    command: "echo {{.a}} {{.b}}"
+`)
+			commands, _, err := LoadCommandsFromYAML(file, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := commandNames(commands); !slices.Equal(got, tt.want) {
+				t.Errorf("names = %v, want %v", got, tt.want)
+			}
+			if got := commands[3].Command; got != "echo 2 y" {
+				t.Errorf("command of the last pane = %q, want %q", got, "echo 2 y")
+			}
+		})
+	}
+}
+
+func TestGroupCommandsKeepsMatrixTogether(t *testing.T) {
+	matrix := &Matrix{}
+	commands := []*Command{
+		{Name: "w1", Matrix: matrix},
+		{Name: "date"},
+		{Name: "w2", Matrix: matrix},
+	}
+
+	groups := GroupCommands(commands)
+	last := groups[len(groups)-1]
+	if got := commandNames(last.Matrix); !slices.Equal(got, []string{"w1", "w2"}) {
+		t.Errorf("matrix group = %v, want [w1 w2]", got)
+	}
+}
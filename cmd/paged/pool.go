@@ -0,0 +1,144 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// workerPool bounds how many commands run at once across every page. Runs
+// waiting for a slot are let in by priority, then in the order they arrived.
+// The pool starts closed so that every pane launched at startup is queued
+// before the first slot is handed out, otherwise whichever goroutine got
+// scheduled first would win regardless of priority.
+type workerPool struct {
+	mu      sync.Mutex
+	open    bool
+	limit   int // 0 = no limit
+	running int
+	waiting waitQueue
+	seq     uint64
+}
+
+func newWorkerPool() *workerPool {
+	return &workerPool{}
+}
+
+// Open starts handing out slots, at most limit at a time (0 = no limit)
+func (p *workerPool) Open(limit int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.open = true
+	p.limit = limit
+	p.dispatch()
+}
+
+// TryAcquire takes a slot if one is free right away
+func (p *workerPool) TryAcquire() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.hasFreeSlot() || len(p.waiting) > 0 {
+		return false
+	}
+	p.running++
+	return true
+}
+
+// Acquire waits for a slot. Every successful call must be paired with Release
+func (p *workerPool) Acquire(ctx context.Context, priority int) error {
+	p.mu.Lock()
+	if p.hasFreeSlot() && len(p.waiting) == 0 {
+		p.running++
+		p.mu.Unlock()
+		return nil
+	}
+
+	w := &waiter{priority: priority, seq: p.seq, ready: make(chan struct{})}
+	p.seq++
+	heap.Push(&p.waiting, w)
+	p.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		// Got the slot while giving up, pass it on
+		p.running--
+		p.dispatch()
+	default:
+		heap.Remove(&p.waiting, w.index)
+	}
+	return ctx.Err()
+}
+
+// Release gives back a slot taken with TryAcquire or Acquire
+func (p *workerPool) Release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.running--
+	p.dispatch()
+}
+
+// hasFreeSlot reports whether another run may start. Callers must hold p.mu
+func (p *workerPool) hasFreeSlot() bool {
+	return p.open && (p.limit == 0 || p.running < p.limit)
+}
+
+// dispatch hands free slots to the waiters first in line. Callers must hold p.mu
+func (p *workerPool) dispatch() {
+	for p.hasFreeSlot() && len(p.waiting) > 0 {
+		w := heap.Pop(&p.waiting).(*waiter)
+		p.running++
+		close(w.ready)
+	}
+}
+
+type waiter struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+	index    int
+}
+
+// waitQueue is a heap of waiters, highest priority first
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int { return len(q) }
+
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waitQueue) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() any {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return w
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// acquireAsync starts waiting for a slot and records name in order once
+// the slot is handed out
+func acquireAsync(t *testing.T, ctx context.Context, p *workerPool, priority int, name string, order *[]string, mu *sync.Mutex) <-chan error {
+	t.Helper()
+	result := make(chan error, 1)
+	go func() {
+		err := p.Acquire(ctx, priority)
+		if err == nil {
+			mu.Lock()
+			*order = append(*order, name)
+			mu.Unlock()
+		}
+		result <- err
+	}()
+	return result
+}
+
+// waitQueued waits until n runs are waiting for a slot
+func waitQueued(t *testing.T, p *workerPool, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		p.mu.Lock()
+		waiting := len(p.waiting)
+		p.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d runs waiting, want %d", waiting, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWorkerPoolPriorityOrder(t *testing.T) {
+	p := newWorkerPool()
+	var mu sync.Mutex
+	var order []string
+
+	// The pool starts closed, so everything queues up first
+	var results []<-chan error
+	for i, w := range []struct {
+		name     string
+		priority int
+	}{{"low", 0}, {"high", 5}, {"low-2", 0}, {"mid", 1}} {
+		results = append(results, acquireAsync(t, context.Background(), p, w.priority, w.name, &order, &mu))
+		waitQueued(t, p, i+1)
+	}
+
+	p.Open(1)
+	for range results {
+		time.Sleep(10 * time.Millisecond)
+		p.Release()
+	}
+	for _, result := range results {
+		if err := <-result; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"high", "mid", "low", "low-2"}
+	if !slices.Equal(order, want) {
+		t.Errorf("slots went to %v, want %v", order, want)
+	}
+}
+
+func TestWorkerPoolLimit(t *testing.T) {
+	p := newWorkerPool()
+	p.Open(2)
+
+	if !p.TryAcquire() || !p.TryAcquire() {
+		t.Fatal("no slot free under the limit")
+	}
+	if p.TryAcquire() {
+		t.Fatal("got a slot past the limit")
+	}
+	p.Release()
+	if !p.TryAcquire() {
+		t.Fatal("no slot free after a release")
+	}
+}
+
+func TestWorkerPoolCancelGivesUpPlace(t *testing.T) {
+	p := newWorkerPool()
+	p.Open(1)
+	if !p.TryAcquire() {
+		t.Fatal("no slot free")
+	}
+
+	var mu sync.Mutex
+	var order []string
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := acquireAsync(t, ctx, p, 10, "cancelled", &order, &mu)
+	waitQueued(t, p, 1)
+	next := acquireAsync(t, context.Background(), p, 0, "next", &order, &mu)
+	waitQueued(t, p, 2)
+
+	cancel()
+	if err := <-cancelled; err != context.Canceled {
+		t.Fatalf("Acquire after cancel = %v, want context.Canceled", err)
+	}
+	waitQueued(t, p, 1)
+
+	p.Release()
+	if err := <-next; err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(order, []string{"next"}) {
+		t.Errorf("slots went to %v, want [next]", order)
+	}
+
+	// Only next holds a slot now
+	p.Release()
+	if !p.TryAcquire() {
+		t.Error("the cancelled run kept a slot")
+	}
+}
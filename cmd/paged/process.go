@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"slices"
+)
+
+// buildExecCmd prepares the process for a run of cmd. Commands given as
+// argv are run directly, everything else goes through the shell
+func buildExecCmd(cmd *Command) *exec.Cmd {
+	args := cmd.Argv
+	if len(args) == 0 {
+		args = append(slices.Clone(cmd.Shell), cmd.Command)
+	}
+	execCmd := exec.Command(args[0], args[1:]...)
+	execCmd.Dir = cmd.Cwd
+	if len(cmd.Env) > 0 {
+		execCmd.Env = append(os.Environ(), cmd.Env...)
+	}
+	return execCmd
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"swissknife/internal/proc"
+)
+
+// ptyDrainTimeout bounds how long output is read after the process exits,
+// a background child still holding the terminal would block us forever
+const ptyDrainTimeout = time.Second
+
+// termSize is the inner size of the pane a command's terminal is shown in.
+// It follows the pane as it is drawn and resizes the terminal of the run in
+// flight along with it
+type termSize struct {
+	mu   sync.Mutex
+	rows uint16
+	cols uint16
+	tty  *os.File // Terminal of the run in flight, nil in between runs
+}
+
+func newTermSize() *termSize {
+	return &termSize{rows: 24, cols: 80}
+}
+
+// track keeps s in sync with the inner size of view
+func (s *termSize) track(view *tview.TextView) {
+	view.SetDrawFunc(func(screen tcell.Screen, x, y, width, height int) (int, int, int, int) {
+		// The pane has a border and no padding
+		width, height = max(width-2, 0), max(height-2, 0)
+		s.resize(height, width)
+		return x + 1, y + 1, width, height
+	})
+}
+
+func (s *termSize) resize(rows, cols int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rows == 0 || cols == 0 || (int(s.rows) == rows && int(s.cols) == cols) {
+		return
+	}
+	s.rows, s.cols = uint16(rows), uint16(cols)
+	if s.tty != nil {
+		_ = pty.Setsize(s.tty, s.winsize())
+	}
+}
+
+// attach makes tty follow the pane size until it is detached
+func (s *termSize) attach(tty *os.File) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tty = tty
+}
+
+func (s *termSize) detach() {
+	s.attach(nil)
+}
+
+// winsize returns the current size. Callers must hold s.mu
+func (s *termSize) winsize() *pty.Winsize {
+	return &pty.Winsize{Rows: s.rows, Cols: s.cols}
+}
+
+// runInTerminal is proc.Run for commands run under a pseudo-terminal sized
+// after their pane. Stdout and stderr both go to the terminal, everything
+// written to it is copied to out as is, line endings included
+func runInTerminal(ctx context.Context, execCmd *exec.Cmd, size *termSize, out io.Writer) error {
+	size.mu.Lock()
+	winsize := size.winsize()
+	size.mu.Unlock()
+
+	// Setsid makes the process lead its own process group as well, so the
+	// group is terminated on cancel just like with proc.Run
+	tty, err := pty.StartWithAttrs(execCmd, winsize, &syscall.SysProcAttr{Setsid: true, Setctty: true})
+	if err != nil {
+		return err
+	}
+	defer tty.Close()
+
+	size.attach(tty)
+	defer size.detach()
+
+	// Hang up first, like closing a terminal window would. Interactive
+	// shells ignore the SIGTERM sent on cancel
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = syscall.Kill(-execCmd.Process.Pid, syscall.SIGHUP)
+		case <-exited:
+		}
+	}()
+
+	copied := make(chan struct{})
+	go func() {
+		// Reading fails with EIO once the process and its children are gone
+		_, _ = io.Copy(out, tty)
+		close(copied)
+	}()
+
+	// Children left in the background may hold the terminal open for good,
+	// so the copy is cut short after a while. It must be over before we
+	// return, as callers read out right away
+	return proc.Wait(ctx, execCmd, func() error {
+		err := execCmd.Wait()
+		select {
+		case <-copied:
+		case <-time.After(ptyDrainTimeout):
+			_ = tty.Close()
+			<-copied
+		}
+		return err
+	})
+}
+
+// newlineWriter turns the CRLF line endings written by a terminal back into LF
+type newlineWriter struct {
+	w  io.Writer
+	cr bool // The last byte written was a CR, held back until we see what follows
+}
+
+func (n *newlineWriter) Write(p []byte) (int, error) {
+	var b bytes.Buffer
+	for _, c := range p {
+		if n.cr && c != '\n' {
+			b.WriteByte('\r')
+		}
+		n.cr = c == '\r'
+		if !n.cr {
+			b.WriteByte(c)
+		}
+	}
+	if _, err := n.w.Write(b.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+)
+
+func TestNewlineWriter(t *testing.T) {
+	var b bytes.Buffer
+	w := &newlineWriter{w: &b}
+	// A CRLF split across writes, and a lone CR that is kept
+	for _, chunk := range []string{"one\r", "\ntwo\r\n", "50%\rdone\r\n"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := "one\ntwo\n50%\rdone\n"; b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
+
+func TestRunInTerminal(t *testing.T) {
+	execCmd := exec.Command("sh", "-c", "stty size; echo hello")
+	var out bytes.Buffer
+	if err := runInTerminal(context.Background(), execCmd, newTermSize(), &out); err != nil {
+		t.Fatal(err)
+	}
+	// Read out like runCommand does, the race detector flags a copy still going
+	if got := out.String(); got != "24 80\r\nhello\r\n" {
+		t.Errorf("output = %q, want the terminal size and hello", got)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// reloadPollInterval is how often the commands files and the files they
+// include are checked for changes
+const reloadPollInterval = time.Second
+
+// maxBannerLines caps the height of the banner showing why a reload failed
+const maxBannerLines = 8
+
+// commandSpec is what a command was loaded from. A reloaded command with the
+// same spec keeps running as it was
+type commandSpec struct {
+	yamlCmd  YAMLCommand // with vars and matrix expanded
+	defaults YAMLDefaults
+	file     string
+}
+
+// runningPane is a pane and what reaches its runner, carried over to the
+// new page when its command is unchanged by a reload
+type runningPane struct {
+	view    tview.Primitive
+	cancel  context.CancelFunc
+	actions chan paneAction
+}
+
+// commandKey identifies a command across reloads: by id, along with the
+// name for the panes of a matrix which share it, otherwise by name
+func commandKey(cmd *Command) string {
+	switch {
+	case cmd.ID == "":
+		return "name:" + cmd.Name
+	case cmd.Matrix != nil:
+		return "id:" + cmd.ID + "/" + cmd.Name
+	default:
+		return "id:" + cmd.ID
+	}
+}
+
+// reusePanes swaps the commands of a reloaded page that didn't change for
+// the ones already running in old, so that they keep their output and
+// history, and returns their panes. The panes of commands that changed or
+// are gone are cancelled
+func reusePanes(old *AppState, commands []*Command) map[*Command]runningPane {
+	running := make(map[string]*Command)
+	panes := make(map[*Command]runningPane)
+	for groupIndex, group := range old.Groups {
+		for paneIndex, cmd := range group.Commands() {
+			key := [2]int{groupIndex, paneIndex}
+			var view tview.Primitive = old.TextViews[groupIndex][paneIndex]
+			if terminal := old.Terminals[key]; terminal != nil {
+				view = terminal
+			}
+			panes[cmd] = runningPane{view: view, cancel: old.CancelFuncs[key], actions: old.Actions[key]}
+			running[commandKey(cmd)] = cmd
+		}
+	}
+
+	kept := make(map[*Command]runningPane)
+	for i, cmd := range commands {
+		previous, ok := running[commandKey(cmd)]
+		if !ok || !reflect.DeepEqual(previous.Spec, cmd.Spec) {
+			continue
+		}
+		commands[i] = previous
+		kept[previous] = panes[previous]
+	}
+
+	for cmd, pane := range panes {
+		if _, ok := kept[cmd]; !ok {
+			pane.cancel()
+		}
+	}
+	return kept
+}
+
+// watchFiles calls reload with the index of each page one of whose files,
+// pageFiles[pageIndex], has its modification time change, and of every page
+// on SIGHUP, until ctx is done. reload returns the files the page now comes
+// from, e.g. with an include added, or nil if they are unknown
+func watchFiles(ctx context.Context, pageFiles [][]string, reload func(pageIndex int) []string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	modTimes := make(map[string]time.Time)
+	watch := func(files []string) {
+		for _, file := range files {
+			if _, ok := modTimes[file]; !ok {
+				modTimes[file] = modTime(file)
+			}
+		}
+	}
+	for _, files := range pageFiles {
+		watch(files)
+	}
+	reloadPage := func(pageIndex int) {
+		if files := reload(pageIndex); files != nil {
+			pageFiles[pageIndex] = files
+			watch(files)
+		}
+	}
+
+	poll := time.NewTicker(reloadPollInterval)
+	defer poll.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			for file := range modTimes {
+				modTimes[file] = modTime(file)
+			}
+			for i := range pageFiles {
+				reloadPage(i)
+			}
+		case <-poll.C:
+			changed := make(map[string]bool)
+			for file, last := range modTimes {
+				// A file being saved may be missing for a moment, wait for it
+				// to be back rather than report it
+				current := modTime(file)
+				if current.IsZero() || current.Equal(last) {
+					continue
+				}
+				modTimes[file] = current
+				changed[file] = true
+			}
+			// A file included by several pages reloads each of them
+			for i, files := range pageFiles {
+				if slices.ContainsFunc(files, func(file string) bool { return changed[file] }) {
+					reloadPage(i)
+				}
+			}
+		}
+	}
+}
+
+// modTime returns when file was last modified, the zero time if it can't be
+// read
+func modTime(file string) time.Time {
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
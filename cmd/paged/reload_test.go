@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	for _, name := range []string{"a.yaml", "b.yaml", "shared.yaml", "only-a.yaml", "new.yaml"} {
+		if err := os.WriteFile(path(name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	touch := func(name string) {
+		t.Helper()
+		later := time.Now().Add(time.Hour)
+		if err := os.Chtimes(path(name), later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pageFiles := [][]string{
+		{path("a.yaml"), path("shared.yaml"), path("only-a.yaml")},
+		{path("b.yaml"), path("shared.yaml")},
+	}
+	reloaded := make(chan int, 10)
+	reload := func(pageIndex int) []string {
+		reloaded <- pageIndex
+		// Page b starts including new.yaml
+		if pageIndex == 1 {
+			return []string{path("b.yaml"), path("shared.yaml"), path("new.yaml")}
+		}
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchFiles(ctx, pageFiles, reload)
+	// Let the watcher note the modification times the files start with
+	time.Sleep(reloadPollInterval / 2)
+
+	expect := func(want ...int) {
+		t.Helper()
+		for _, pageIndex := range want {
+			select {
+			case got := <-reloaded:
+				if got != pageIndex {
+					t.Fatalf("reloaded page %d, want %d", got, pageIndex)
+				}
+			case <-time.After(3 * reloadPollInterval):
+				t.Fatalf("page %d wasn't reloaded", pageIndex)
+			}
+		}
+		select {
+		case got := <-reloaded:
+			t.Fatalf("reloaded page %d too", got)
+		case <-time.After(reloadPollInterval + reloadPollInterval/2):
+		}
+	}
+
+	touch("only-a.yaml")
+	expect(0)
+	touch("shared.yaml")
+	expect(0, 1)
+	touch("new.yaml")
+	expect(1)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	defaultRetryDelay      = time.Second
+	defaultRetryMultiplier = 2
+)
+
+// RetryPolicy retries a failed run with exponential backoff before the
+// failure is shown in the pane
+type RetryPolicy struct {
+	Attempts   int           // Total attempts, including the first run
+	Delay      time.Duration // Wait before the first retry
+	Multiplier float64       // Factor applied to the wait after each retry
+	MaxDelay   time.Duration // Upper bound for the wait (0 = no limit)
+}
+
+// YAMLRetry is the `retry` block of a command
+type YAMLRetry struct {
+	Attempts   int      `yaml:"attempts"`
+	Delay      Duration `yaml:"delay"`
+	Multiplier float64  `yaml:"multiplier"`
+	MaxDelay   Duration `yaml:"max_delay"`
+}
+
+// newRetryPolicy fills in defaults for the settings left out of r
+func newRetryPolicy(r *YAMLRetry) (*RetryPolicy, error) {
+	if r.Attempts < 1 {
+		return nil, fmt.Errorf("retry attempts must be at least 1, got %d", r.Attempts)
+	}
+	if r.Multiplier < 0 || (r.Multiplier > 0 && r.Multiplier < 1) {
+		return nil, fmt.Errorf("retry multiplier must be at least 1, got %v", r.Multiplier)
+	}
+
+	policy := &RetryPolicy{
+		Attempts:   r.Attempts,
+		Delay:      time.Duration(r.Delay),
+		Multiplier: r.Multiplier,
+		MaxDelay:   time.Duration(r.MaxDelay),
+	}
+	if policy.Delay == 0 {
+		policy.Delay = defaultRetryDelay
+	}
+	if policy.Multiplier == 0 {
+		policy.Multiplier = defaultRetryMultiplier
+	}
+
+	return policy, nil
+}
+
+// backoff returns how long to wait after the given failed attempt, counting
+// from 1
+func (r *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(r.Delay) * math.Pow(r.Multiplier, float64(attempt-1))
+	if r.MaxDelay > 0 && delay > float64(r.MaxDelay) {
+		return r.MaxDelay
+	}
+	if delay >= math.MaxInt64 { // float64(math.MaxInt64) is 2^63, one past it
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNewRetryPolicy(t *testing.T) {
+	policy, err := newRetryPolicy(&YAMLRetry{Attempts: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RetryPolicy{Attempts: 3, Delay: defaultRetryDelay, Multiplier: defaultRetryMultiplier}
+	if *policy != want {
+		t.Errorf("policy = %+v, want %+v", *policy, want)
+	}
+
+	for _, retry := range []YAMLRetry{
+		{Attempts: 0},
+		{Attempts: 3, Multiplier: 0.5},
+		{Attempts: 3, Multiplier: -2},
+	} {
+		if _, err := newRetryPolicy(&retry); err == nil {
+			t.Errorf("accepted %+v", retry)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := &RetryPolicy{Attempts: 10, Delay: time.Second, Multiplier: 2, MaxDelay: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 9: 5 * time.Second} {
+		if got := policy.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+
+	// Without a cap the wait grows past what a Duration holds
+	policy.MaxDelay = 0
+	if got := policy.backoff(100); got != time.Duration(math.MaxInt64) {
+		t.Errorf("backoff(100) = %v, want the longest duration", got)
+	}
+	policy.Delay, policy.Multiplier = time.Duration(1<<62), 2
+	if got := policy.backoff(2); got != time.Duration(math.MaxInt64) {
+		t.Errorf("backoff(2) of 2^62 = %v, want the longest duration", got)
+	}
+}
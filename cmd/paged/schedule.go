@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Overlap policies for a repeating command whose previous run is still going
+// when its next tick comes up
+const (
+	OverlapSkip         = "skip"          // drop the tick
+	OverlapQueue        = "queue"         // run once the previous run is done
+	OverlapKillPrevious = "kill-previous" // kill the previous run and start over
+)
+
+// ticker sends on C every time its schedule comes up. Ticks are computed
+// from the wall clock rather than by sleeping between runs, so they don't
+// drift with the time each run takes. A random offset picked once from
+// [0, jitter) spreads out commands that share a schedule instead of having
+// them all fork at once.
+type ticker struct {
+	C <-chan time.Time
+
+	c    chan time.Time
+	stop chan struct{}
+	next func(now time.Time) time.Time
+
+	mu     sync.Mutex
+	nextAt time.Time
+}
+
+// newIntervalTicker ticks on multiples of interval since the Unix epoch,
+// e.g. :00, :05, :10 for 5s
+func newIntervalTicker(interval, jitter time.Duration) *ticker {
+	offset := pickOffset(min(jitter, interval))
+	return startTicker(func(now time.Time) time.Time {
+		return nextInterval(now, interval, offset)
+	})
+}
+
+// nextInterval returns the first multiple of interval since the Unix epoch,
+// shifted by offset, after now. Time.Truncate counts from the zero time
+// instead, which only agrees with the epoch for intervals dividing a day
+func nextInterval(now time.Time, interval, offset time.Duration) time.Time {
+	since := now.Add(-offset).UnixNano()
+	last := since - since%int64(interval)
+	return time.Unix(0, last).Add(interval + offset)
+}
+
+// newCronTicker ticks whenever schedule comes up. A schedule that never
+// comes up again, like Feb 30, stops the ticker
+func newCronTicker(schedule cron.Schedule, jitter time.Duration) *ticker {
+	offset := pickOffset(jitter)
+	return startTicker(func(now time.Time) time.Time {
+		next := schedule.Next(now.Add(-offset))
+		if next.IsZero() {
+			return next
+		}
+		return next.Add(offset)
+	})
+}
+
+func pickOffset(jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return 0
+	}
+	return rand.N(jitter)
+}
+
+func startTicker(next func(now time.Time) time.Time) *ticker {
+	t := &ticker{
+		c:    make(chan time.Time, 1),
+		stop: make(chan struct{}),
+		next: next,
+	}
+	t.C = t.c
+
+	go t.run()
+	return t
+}
+
+func (t *ticker) run() {
+	wait, ok := t.schedule(time.Now())
+	if !ok {
+		return
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case now := <-timer.C:
+			select {
+			case t.c <- now:
+			default:
+				// The previous tick hasn't been picked up, no point piling up more
+			}
+			if wait, ok = t.schedule(now); !ok {
+				return
+			}
+			timer.Reset(wait)
+		}
+	}
+}
+
+// schedule records the first tick after now and returns how long until
+// then, or false if there is none
+func (t *ticker) schedule(now time.Time) (time.Duration, bool) {
+	nextAt := t.next(now)
+
+	t.mu.Lock()
+	t.nextAt = nextAt
+	t.mu.Unlock()
+
+	return nextAt.Sub(now), !nextAt.IsZero()
+}
+
+// Next returns when the ticker fires next, the zero time if it never will
+func (t *ticker) Next() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.nextAt
+}
+
+// Stop stops the ticker, it must not be called more than once
+func (t *ticker) Stop() {
+	close(t.stop)
+}
+
+// formatCountdown renders when next comes up, e.g. `next 14:05:00 (in 2m13s)`
+func formatCountdown(next time.Time) string {
+	return fmt.Sprintf("next %s (in %s)", next.Format("15:04:05"), time.Until(next).Round(time.Second))
+}
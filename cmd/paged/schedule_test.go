@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+func TestCronTickerStopsWhenScheduleNeverComesUp(t *testing.T) {
+	schedule, err := cron.ParseStandard("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ticks := newCronTicker(schedule, 0)
+	defer ticks.Stop()
+
+	select {
+	case <-ticks.C:
+		t.Fatal("ticked for a schedule that never comes up")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if next := ticks.Next(); !next.IsZero() {
+		t.Errorf("Next() = %v, want the zero time", next)
+	}
+}
+
+func TestLoadCommandsRejectsScheduleThatNeverComesUp(t *testing.T) {
+	file := writeConfig(t, "a.yaml", `
+commands:
+  - name: never
+    command: date
+    schedule: "0 0 30 2 *"
+`)
+	_, _, err := LoadCommandsFromYAML(file, nil)
+	if err == nil {
+		t.Fatal("loaded a schedule that never comes up")
+	}
+	if want := `a.yaml:5:15: schedule "0 0 30 2 *" never comes up`; !containsLine(err.Error(), want) {
+		t.Errorf("error = %v, want %q", err, want)
+	}
+}
+
+func TestNextInterval(t *testing.T) {
+	epoch := func(seconds float64) time.Time {
+		return time.Unix(0, int64(seconds*float64(time.Second)))
+	}
+	tests := []struct {
+		now      float64
+		interval time.Duration
+		offset   time.Duration
+		want     float64
+	}{
+		{now: 1000, interval: 5 * time.Second, want: 1005},
+		{now: 1003.5, interval: 5 * time.Second, want: 1005},
+		{now: 1000, interval: 7 * time.Second, want: 1001}, // 143 * 7 = 1001
+		{now: 1001, interval: 7 * time.Second, want: 1008},
+		{now: 1000, interval: 5 * time.Second, offset: 2 * time.Second, want: 1002},
+		{now: 1002, interval: 5 * time.Second, offset: 2 * time.Second, want: 1007},
+	}
+	for _, tt := range tests {
+		got := nextInterval(epoch(tt.now), tt.interval, tt.offset)
+		if !got.Equal(epoch(tt.want)) {
+			t.Errorf("nextInterval(%v, %v, %v) = %v, want %v", tt.now, tt.interval, tt.offset,
+				float64(got.UnixNano())/float64(time.Second), tt.want)
+		}
+	}
+}
+
+func TestIntervalTickerJitter(t *testing.T) {
+	now := time.Now()
+	for range 50 {
+		ticks := newIntervalTicker(7*time.Second, 3*time.Second)
+		ticks.Stop()
+		next := ticks.next(now)
+
+		// The offset is what is left past the last multiple of 7s
+		offset := time.Duration(next.UnixNano() % int64(7*time.Second))
+		if offset < 0 || offset >= 3*time.Second {
+			t.Fatalf("tick at %v is %v past a multiple of 7s, want less than the 3s jitter", next, offset)
+		}
+		if !next.After(now) || next.Sub(now) > 7*time.Second {
+			t.Fatalf("tick at %v, want within 7s after %v", next, now)
+		}
+	}
+
+	// Jitter longer than the interval is capped at it, so ticks still come
+	// once per interval
+	for range 50 {
+		ticks := newIntervalTicker(time.Second, time.Minute)
+		ticks.Stop()
+		if next := ticks.next(now); !next.After(now) || next.Sub(now) > time.Second {
+			t.Fatalf("tick at %v, want within 1s after %v", next, now)
+		}
+	}
+}
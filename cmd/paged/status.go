@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// Outcomes of a run, stored in Command.Status. Anything else is the error
+// that kept the process from starting
+const (
+	StatusCompleted = "Completed"
+	StatusFailed    = "Failed"
+	StatusTimedOut  = "Timed out"
+	StatusKilled    = "Killed"
+)
+
+// startRun marks cmd as running. Callers must hold the mutex guarding cmd
+func startRun(cmd *Command) time.Time {
+	cmd.IsRunning = true
+	return time.Now()
+}
+
+// recordRun stores the outcome of a run that began at startedAt. Callers
+// must hold the mutex guarding cmd
+func recordRun(cmd *Command, startedAt time.Time, err error) {
+	cmd.IsRunning = false
+	cmd.Runs++
+	cmd.StartedAt = startedAt
+	cmd.FinishedAt = time.Now()
+	cmd.Duration = cmd.FinishedAt.Sub(startedAt)
+	cmd.ExitCode = 0
+
+	var exitErr *exec.ExitError
+	var execErr *exec.Error
+	switch {
+	case err == nil:
+		cmd.Status = StatusCompleted
+	case errors.Is(err, context.DeadlineExceeded):
+		cmd.Status = StatusTimedOut
+		cmd.ExitCode = -1
+	case errors.As(err, &exitErr):
+		cmd.Status = StatusFailed
+		cmd.ExitCode = exitErr.ExitCode()
+	case errors.As(err, &execErr) && errors.Is(execErr.Err, exec.ErrNotFound):
+		cmd.Status = fmt.Sprintf("binary not found: %s", execErr.Name)
+		cmd.ExitCode = -1
+	default:
+		cmd.Status = err.Error()
+		cmd.ExitCode = -1
+	}
+
+	if err != nil {
+		cmd.Failures++
+	} else {
+		cmd.Failures = 0
+	}
+}
+
+// recordKill marks cmd as killed. Callers must hold the mutex guarding cmd
+func recordKill(cmd *Command) {
+	cmd.IsRunning = false
+	cmd.Status = StatusKilled
+	cmd.FinishedAt = time.Now()
+}
+
+// formatHeader renders the one-line summary shown at the top of a pane,
+// e.g. `exit 0 · 132ms · run #42 · 14:02:11`. Callers must hold the mutex
+// guarding cmd
+func formatHeader(cmd *Command) string {
+	header := formatRunHeader(RunRecord{
+		Run:        cmd.Runs,
+		Status:     cmd.Status,
+		ExitCode:   cmd.ExitCode,
+		FinishedAt: cmd.FinishedAt,
+		Duration:   cmd.Duration,
+	})
+	if cmd.Status != StatusKilled && cmd.Failures > 1 {
+		header += fmt.Sprintf(" · [red]%d failures in a row[-]", cmd.Failures)
+	}
+	return header
+}
+
+// formatRunHeader renders the summary of a single run
+func formatRunHeader(run RunRecord) string {
+	var state string
+	switch run.Status {
+	case StatusCompleted:
+		state = "[green]exit 0[-]"
+	case StatusFailed:
+		state = fmt.Sprintf("[red]exit %d[-]", run.ExitCode)
+	case StatusTimedOut:
+		state = "[yellow]timed out[-]"
+	case StatusKilled:
+		return fmt.Sprintf("[yellow]killed[-] · %s", run.FinishedAt.Format("15:04:05"))
+	default:
+		state = fmt.Sprintf("[red]%s[-]", tview.Escape(run.Status))
+	}
+
+	return strings.Join([]string{
+		state,
+		run.Duration.Round(time.Millisecond).String(),
+		fmt.Sprintf("run #%d", run.Run),
+		run.FinishedAt.Format("15:04:05"),
+	}, " · ")
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestRecordRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		status   string
+		exitCode int
+		failures int
+	}{
+		{name: "completed", err: nil, status: StatusCompleted, exitCode: 0, failures: 0},
+		{name: "failed", err: exec.Command("sh", "-c", "exit 3").Run(), status: StatusFailed, exitCode: 3, failures: 1},
+		{name: "timed out", err: fmt.Errorf("run: %w", context.DeadlineExceeded), status: StatusTimedOut, exitCode: -1, failures: 1},
+		{name: "binary not found", err: &exec.Error{Name: "kubectl", Err: exec.ErrNotFound}, status: "binary not found: kubectl", exitCode: -1, failures: 1},
+		{name: "other error", err: errors.New("fork failed"), status: "fork failed", exitCode: -1, failures: 1},
+	}
+	for _, tt := range tests {
+		cmd := &Command{}
+		recordRun(cmd, startRun(cmd), tt.err)
+		if cmd.Status != tt.status || cmd.ExitCode != tt.exitCode || cmd.Failures != tt.failures || cmd.Runs != 1 || cmd.IsRunning {
+			t.Errorf("%s: status %q, exit %d, %d failures, %d runs, running %v, want status %q, exit %d, %d failures",
+				tt.name, cmd.Status, cmd.ExitCode, cmd.Failures, cmd.Runs, cmd.IsRunning, tt.status, tt.exitCode, tt.failures)
+		}
+	}
+}
+
+func TestRecordRunFailuresInARow(t *testing.T) {
+	cmd := &Command{}
+	failed := errors.New("failed")
+	for _, err := range []error{failed, failed, failed} {
+		recordRun(cmd, time.Now(), err)
+	}
+	if cmd.Failures != 3 {
+		t.Errorf("failures = %d, want 3", cmd.Failures)
+	}
+	recordRun(cmd, time.Now(), nil)
+	if cmd.Failures != 0 {
+		t.Errorf("failures = %d after a success, want 0", cmd.Failures)
+	}
+}
+
+func TestBinaryNotFound(t *testing.T) {
+	cmd := &Command{}
+	recordRun(cmd, time.Now(), exec.Command("swissknife-no-such-binary").Run())
+	if want := "binary not found: swissknife-no-such-binary"; cmd.Status != want {
+		t.Errorf("status = %q, want %q", cmd.Status, want)
+	}
+}
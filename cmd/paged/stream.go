@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"swissknife/internal/proc"
+)
+
+// ModeStream marks a command whose output is shown as it is produced,
+// like `tail -f` or `vmstat 1`, instead of once the process exits
+const ModeStream = "stream"
+
+// Restart policies for streaming commands once their process exits
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+const (
+	// defaultScrollback is the number of lines a streaming pane keeps
+	defaultScrollback = 1000
+
+	// streamFlushInterval batches output lines so a chatty process
+	// doesn't queue one redraw per line
+	streamFlushInterval = 100 * time.Millisecond
+
+	// streamRestartDelay is the pause before a stream is restarted
+	streamRestartDelay = 2 * time.Second
+)
+
+// StreamCommand runs a long-lived command and appends its output to the pane
+// as it arrives, restarting it according to cmd.Restart once it exits. From
+// the keyboard, a stream can be restarted right away or stopped until rerun
+func StreamCommand(ctx context.Context, cmd *Command, output *tview.TextView, mu *sync.Mutex, app *tview.Application, actions <-chan paneAction) {
+	// stop leaves the stream down until it is rerun from the keyboard
+	stop := func(reason string) {
+		proc.QueueDraw(ctx, app, func() {
+			output.SetTitle(fmt.Sprintf("Ended: %s", cmd.Name))
+			fmt.Fprintf(output, "\n%s\n", reason)
+		})
+		waitForRerun(ctx, actions)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			mu.Lock()
+			recordKill(cmd)
+			mu.Unlock()
+			proc.QueueDraw(ctx, app, func() {
+				output.SetTitle(fmt.Sprintf("Killed: %s", cmd.Name))
+				fmt.Fprintln(output, "Job terminated.")
+			})
+			log.Println("cancelling", cmd.Command)
+			return
+		default:
+		}
+
+		proc.QueueDraw(ctx, app, func() {
+			output.SetTitle(fmt.Sprintf("Live: %s", cmd.Name))
+		})
+
+		mu.Lock()
+		startedAt := startRun(cmd)
+		mu.Unlock()
+
+		runCtx, cancelRun := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() {
+			done <- streamProcess(runCtx, cmd, output, app)
+		}()
+
+		var err error
+		interrupted, stopped := false, false
+	waiting:
+		for {
+			select {
+			case err = <-done:
+				break waiting
+			case action := <-actions:
+				switch action {
+				case actionRerun:
+					interrupted = true
+					cancelRun()
+				case actionKillRun:
+					interrupted, stopped = true, true
+					cancelRun()
+				}
+			}
+		}
+		cancelRun()
+		if ctx.Err() != nil {
+			continue
+		}
+
+		if interrupted {
+			mu.Lock()
+			recordKill(cmd)
+			mu.Unlock()
+			if stopped {
+				stop("Job terminated.")
+			}
+			continue
+		}
+
+		mu.Lock()
+		recordRun(cmd, startedAt, err)
+		status := formatHeader(cmd)
+		mu.Unlock()
+
+		restart := cmd.Restart == RestartAlways || (cmd.Restart == RestartOnFailure && err != nil)
+		if !restart {
+			stop(status)
+			continue
+		}
+
+		proc.QueueDraw(ctx, app, func() {
+			output.SetTitle(fmt.Sprintf("Restarting: %s", cmd.Name))
+			fmt.Fprintf(output, "\n%s, restarting in %s\n", status, streamRestartDelay)
+		})
+
+		timer := time.NewTimer(streamRestartDelay)
+	delay:
+		for {
+			select {
+			case <-ctx.Done():
+				break delay
+			case <-timer.C:
+				break delay
+			case action := <-actions:
+				switch action {
+				case actionRerun:
+					break delay
+				case actionKillRun:
+					stop("Restart cancelled.")
+					break delay
+				}
+			}
+		}
+		timer.Stop()
+	}
+}
+
+// waitForRerun blocks until the pane is rerun from the keyboard or ctx is
+// cancelled
+func waitForRerun(ctx context.Context, actions <-chan paneAction) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case action := <-actions:
+			if action == actionRerun {
+				return
+			}
+		}
+	}
+}
+
+// streamProcess runs cmd once, copying its stdout and stderr into output in
+// small batches until the process exits. Stderr lines are shown in red
+func streamProcess(ctx context.Context, cmd *Command, output *tview.TextView, app *tview.Application) error {
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	execCmd := buildExecCmd(cmd)
+
+	lines := make(chan string, 256)
+	var readers sync.WaitGroup
+	readLines := func(r io.Reader, isStderr bool) {
+		defer readers.Done()
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			switch {
+			case line == "":
+			case !isStderr:
+				lines <- renderOutput(line, cmd.ANSI)
+			case !cmd.HideStderr:
+				text, newline := strings.CutSuffix(line, "\n")
+				line = fmt.Sprintf("[red]%s[-]", renderOutput(text, cmd.ANSI))
+				if newline {
+					line += "\n"
+				}
+				lines <- line
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+	readers.Add(2)
+	go readLines(stdoutR, false)
+	go readLines(stderrR, true)
+	go func() {
+		readers.Wait()
+		close(lines)
+	}()
+
+	errc := make(chan error, 1)
+	go func() {
+		if cmd.PTY {
+			errc <- runInTerminal(ctx, execCmd, cmd.Term, &newlineWriter{w: stdoutW})
+		} else {
+			execCmd.Stdout = stdoutW
+			execCmd.Stderr = stderrW
+			errc <- proc.Run(ctx, execCmd)
+		}
+		stdoutW.Close()
+		stderrW.Close()
+	}()
+
+	var pending strings.Builder
+	flush := func() {
+		if pending.Len() == 0 {
+			return
+		}
+		chunk := pending.String()
+		pending.Reset()
+		proc.QueueDraw(ctx, app, func() {
+			fmt.Fprint(output, chunk)
+		})
+	}
+
+	ticker := time.NewTicker(streamFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				flush()
+				return <-errc
+			}
+			pending.WriteString(line)
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
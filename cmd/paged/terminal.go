@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/hinshun/vt10x"
+	"github.com/rivo/tview"
+
+	"swissknife/internal/proc"
+)
+
+// TypeTerminal marks a pane hosting an interactive program such as htop,
+// psql or a shell, instead of showing the output of a command
+const TypeTerminal = "terminal"
+
+// terminalReleaseKey gives the keyboard back to swissknife while a terminal
+// pane has it
+const terminalReleaseKey = tcell.KeyCtrlRightSq
+
+// Glyph attributes of vt10x, which doesn't export them
+const (
+	vtReverse = 1 << iota
+	vtUnderline
+	vtBold
+	_ // Line drawing character set
+	vtItalic
+	vtBlink
+)
+
+// terminalPane is a pane emulating a terminal for the program running in it.
+// While it has focus, every key but terminalReleaseKey is sent to the program
+type terminalPane struct {
+	*tview.Box
+	vt   vt10x.Terminal
+	size *termSize
+}
+
+func newTerminalPane() *terminalPane {
+	t := &terminalPane{
+		Box:  tview.NewBox(),
+		size: newTermSize(),
+	}
+	// The emulator answers queries such as the cursor position to the program
+	t.vt = vt10x.New(vt10x.WithWriter(t), vt10x.WithSize(int(t.size.cols), int(t.size.rows)))
+	return t
+}
+
+// Write sends p to the program running in the pane, if any
+func (t *terminalPane) Write(p []byte) (int, error) {
+	t.size.mu.Lock()
+	defer t.size.mu.Unlock()
+	if t.size.tty == nil {
+		return len(p), nil
+	}
+	return t.size.tty.Write(p)
+}
+
+// Draw renders the emulated screen, resizing it to the pane first
+func (t *terminalPane) Draw(screen tcell.Screen) {
+	t.Box.DrawForSubclass(screen, t)
+	x, y, width, height := t.GetInnerRect()
+	if width <= 0 || height <= 0 {
+		return
+	}
+	if cols, rows := t.vt.Size(); cols != width || rows != height {
+		t.vt.Resize(width, height)
+		t.size.resize(height, width)
+	}
+
+	t.vt.Lock()
+	defer t.vt.Unlock()
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			glyph := t.vt.Cell(col, row)
+			char := glyph.Char
+			if char == 0 {
+				char = ' '
+			}
+			screen.SetContent(x+col, y+row, char, nil, glyphStyle(glyph))
+		}
+	}
+	if cursor := t.vt.Cursor(); t.HasFocus() && t.vt.CursorVisible() && cursor.X < width && cursor.Y < height {
+		screen.ShowCursor(x+cursor.X, y+cursor.Y)
+	}
+}
+
+// InputHandler sends keys to the program running in the pane
+func (t *terminalPane) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return t.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if input := keyInput(event, t.vt.Mode()&vt10x.ModeAppCursor != 0); len(input) > 0 {
+			_, _ = t.Write(input)
+		}
+	})
+}
+
+// glyphStyle converts the colors and attributes of an emulated cell
+func glyphStyle(glyph vt10x.Glyph) tcell.Style {
+	style := tcell.StyleDefault.
+		Foreground(vtColor(glyph.FG)).
+		Background(vtColor(glyph.BG)).
+		Reverse(glyph.Mode&vtReverse != 0).
+		Underline(glyph.Mode&vtUnderline != 0).
+		Bold(glyph.Mode&vtBold != 0).
+		Italic(glyph.Mode&vtItalic != 0).
+		Blink(glyph.Mode&vtBlink != 0)
+	return style
+}
+
+func vtColor(c vt10x.Color) tcell.Color {
+	switch {
+	case c < 256:
+		return tcell.PaletteColor(int(c))
+	case c < 1<<24:
+		return tcell.NewHexColor(int32(c))
+	default:
+		return tcell.ColorDefault
+	}
+}
+
+// keyInput returns the bytes a terminal sends for a key press. Cursor keys
+// differ when the program switched the terminal to application cursor mode
+func keyInput(event *tcell.EventKey, appCursor bool) []byte {
+	var input string
+	switch key := event.Key(); key {
+	case tcell.KeyRune:
+		input = string(event.Rune())
+	case tcell.KeyUp, tcell.KeyDown, tcell.KeyRight, tcell.KeyLeft:
+		code := map[tcell.Key]string{tcell.KeyUp: "A", tcell.KeyDown: "B", tcell.KeyRight: "C", tcell.KeyLeft: "D"}[key]
+		if appCursor {
+			return []byte("\x1bO" + code)
+		}
+		input = "\x1b[" + code
+	case tcell.KeyHome:
+		input = "\x1b[H"
+	case tcell.KeyEnd:
+		input = "\x1b[F"
+	case tcell.KeyInsert:
+		input = "\x1b[2~"
+	case tcell.KeyDelete:
+		input = "\x1b[3~"
+	case tcell.KeyPgUp:
+		input = "\x1b[5~"
+	case tcell.KeyPgDn:
+		input = "\x1b[6~"
+	case tcell.KeyBacktab:
+		input = "\x1b[Z"
+	case tcell.KeyF1, tcell.KeyF2, tcell.KeyF3, tcell.KeyF4:
+		input = "\x1bO" + string(rune('P'+key-tcell.KeyF1))
+	case tcell.KeyF5, tcell.KeyF6, tcell.KeyF7, tcell.KeyF8, tcell.KeyF9, tcell.KeyF10, tcell.KeyF11, tcell.KeyF12:
+		codes := []int{15, 17, 18, 19, 20, 21, 23, 24}
+		input = fmt.Sprintf("\x1b[%d~", codes[key-tcell.KeyF5])
+	default:
+		// Control characters, Enter, Tab, Esc and Backspace are sent as is
+		if key >= 128 {
+			return nil
+		}
+		input = string(rune(key))
+	}
+	if event.Modifiers()&tcell.ModAlt != 0 {
+		input = "\x1b" + input
+	}
+	return []byte(input)
+}
+
+// RunTerminal runs cmd in pane under a pseudo-terminal. Once the program
+// exits, the pane keeps its last screen until it is rerun from the keyboard
+func RunTerminal(ctx context.Context, cmd *Command, pane *terminalPane, mu *sync.Mutex, app *tview.Application, actions <-chan paneAction) {
+	setTitle := func(title string) {
+		proc.QueueDraw(ctx, app, func() {
+			pane.SetTitle(title)
+		})
+	}
+
+	// redraw shows what the program wrote once the emulator took it in
+	redraw := writerFunc(func(p []byte) (int, error) {
+		n, err := pane.vt.Write(p)
+		proc.QueueDraw(ctx, app, func() {})
+		return n, err
+	})
+
+	for {
+		// Start each run on a clean screen
+		_, _ = pane.vt.Write([]byte("\x1bc"))
+
+		execCmd := buildExecCmd(cmd)
+		execCmd.Env = append(append(os.Environ(), "TERM=xterm-256color"), cmd.Env...)
+
+		mu.Lock()
+		startedAt := startRun(cmd)
+		title := paneTitle(cmd)
+		mu.Unlock()
+		setTitle(title)
+
+		runCtx, cancelRun := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() {
+			done <- runInTerminal(runCtx, execCmd, pane.size, redraw)
+		}()
+
+		var err error
+		killed, rerun := false, false
+	running:
+		for {
+			select {
+			case err = <-done:
+				break running
+			case action := <-actions:
+				switch action {
+				case actionRerun:
+					killed, rerun = true, true
+					cancelRun()
+				case actionKillRun:
+					killed = true
+					cancelRun()
+				}
+			}
+		}
+		cancelRun()
+		if ctx.Err() != nil {
+			mu.Lock()
+			recordKill(cmd)
+			mu.Unlock()
+			log.Println("cancelling", cmd.Command)
+			return
+		}
+
+		mu.Lock()
+		if killed {
+			recordKill(cmd)
+		} else {
+			recordRun(cmd, startedAt, err)
+		}
+		title = fmt.Sprintf("Ended: %s · %s", cmd.Name, formatHeader(cmd))
+		mu.Unlock()
+		if rerun {
+			continue
+		}
+		setTitle(title)
+
+		waitForRerun(ctx, actions)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// writerFunc adapts a function to io.Writer
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
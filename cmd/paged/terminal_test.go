@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestKeyInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		event     *tcell.EventKey
+		appCursor bool
+		want      string
+	}{
+		{name: "rune", event: tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone), want: "a"},
+		{name: "ctrl-c", event: tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModNone), want: "\x03"},
+		{name: "ctrl-d", event: tcell.NewEventKey(tcell.KeyCtrlD, 0, tcell.ModNone), want: "\x04"},
+		{name: "enter", event: tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone), want: "\r"},
+		{name: "escape", event: tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone), want: "\x1b"},
+		{name: "up", event: tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone), want: "\x1b[A"},
+		{name: "up in application cursor mode", event: tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone), appCursor: true, want: "\x1bOA"},
+		{name: "delete", event: tcell.NewEventKey(tcell.KeyDelete, 0, tcell.ModNone), want: "\x1b[3~"},
+		{name: "f1", event: tcell.NewEventKey(tcell.KeyF1, 0, tcell.ModNone), want: "\x1bOP"},
+		{name: "f5", event: tcell.NewEventKey(tcell.KeyF5, 0, tcell.ModNone), want: "\x1b[15~"},
+		{name: "alt-b", event: tcell.NewEventKey(tcell.KeyRune, 'b', tcell.ModAlt), want: "\x1bb"},
+		{name: "no sequence", event: tcell.NewEventKey(tcell.KeyF20, 0, tcell.ModNone), want: ""},
+	}
+	for _, tt := range tests {
+		if got := string(keyInput(tt.event, tt.appCursor)); got != tt.want {
+			t.Errorf("%s: keyInput = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configErrors collects every problem found in a commands file and the
+// files it includes, so they can all be fixed in one go. Each one cites the
+// file, line and column
+type configErrors struct {
+	file  string                // The page file, where problems are reported by default
+	files map[*yaml.Node]string // File each node was read from, see register
+	errs  []configError
+}
+
+type configError struct {
+	file         string
+	line, column int // column is 0 when yaml.v3 only gave the line
+	msg          string
+}
+
+func newConfigErrors(file string) *configErrors {
+	return &configErrors{file: file, files: make(map[*yaml.Node]string)}
+}
+
+// Error lists the problems in the order they appear in each file
+func (e *configErrors) Error() string {
+	slices.SortStableFunc(e.errs, func(a, b configError) int {
+		return cmp.Or(strings.Compare(a.file, b.file), cmp.Compare(a.line, b.line), cmp.Compare(a.column, b.column))
+	})
+
+	lines := make([]string, 0, len(e.errs))
+	for _, err := range e.errs {
+		switch {
+		case err.column > 0:
+			lines = append(lines, fmt.Sprintf("%s:%d:%d: %s", err.file, err.line, err.column, err.msg))
+		case err.line > 0:
+			lines = append(lines, fmt.Sprintf("%s:%d: %s", err.file, err.line, err.msg))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %s", err.file, err.msg))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// register records that node and everything below it were read from file
+func (e *configErrors) register(node *yaml.Node, file string) {
+	e.files[node] = file
+	for _, child := range node.Content {
+		e.register(child, file)
+	}
+}
+
+// add reports a problem with the value at node. A problem found again, e.g.
+// in each pane expanded from a matrix, is reported once
+func (e *configErrors) add(node *yaml.Node, format string, args ...any) {
+	file, ok := e.files[node]
+	if !ok {
+		file = e.file
+	}
+	err := configError{file: file, line: node.Line, column: node.Column, msg: fmt.Sprintf(format, args...)}
+	if !slices.Contains(e.errs, err) {
+		e.errs = append(e.errs, err)
+	}
+}
+
+// typeErrorPosition matches the position at the start of a decoding error,
+// either ours from nodeError or yaml's own "line N:"
+var typeErrorPosition = regexp.MustCompile(`^(?:(\d+):(\d+)|line (\d+)): `)
+
+// addTypeError reports the problems found while decoding part of file
+func (e *configErrors) addTypeError(file string, err *yaml.TypeError) {
+	for _, msg := range err.Errors {
+		pos := configError{file: file}
+		if m := typeErrorPosition.FindStringSubmatch(msg); m != nil {
+			pos.line, _ = strconv.Atoi(m[1] + m[3])
+			pos.column, _ = strconv.Atoi(m[2])
+			msg = msg[len(m[0]):]
+		}
+		pos.msg = msg
+		e.errs = append(e.errs, pos)
+	}
+}
+
+// err returns e if any problem was found
+func (e *configErrors) err() error {
+	if len(e.errs) == 0 {
+		return nil
+	}
+	return e
+}
+
+// nodeError reports a problem with the value at node from an UnmarshalYAML
+// method. yaml.v3 keeps decoding past a *yaml.TypeError, so every problem
+// in a file is reported at once
+func nodeError(node *yaml.Node, format string, args ...any) error {
+	return &yaml.TypeError{Errors: []string{
+		fmt.Sprintf("%d:%d: %s", node.Line, node.Column, fmt.Sprintf(format, args...)),
+	}}
+}
+
+// mappingValue returns the value of key in a mapping node, nil if it isn't set
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// fieldNode returns where a problem with the field key of node is reported:
+// its value if it is set, node itself otherwise
+func fieldNode(node *yaml.Node, key string) *yaml.Node {
+	if value := mappingValue(node, key); value != nil {
+		return value
+	}
+	return node
+}
+
+var (
+	unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	layoutNodeType  = reflect.TypeOf(LayoutNode{})
+	nodeType        = reflect.TypeOf(yaml.Node{})
+)
+
+// checkFields reports the mapping keys below node that match no field of t,
+// the type node is decoded into, such as a misspelled `comand:`
+func checkFields(node *yaml.Node, t reflect.Type, errs *configErrors) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Types decoding themselves know what they accept, except for the
+	// layout whose mapping form is decoded as a plain struct. Raw nodes are
+	// checked once they are decoded
+	if (reflect.PointerTo(t).Implements(unmarshalerType) && t != layoutNodeType) || t == nodeType {
+		return
+	}
+
+	switch node.Kind {
+	case yaml.SequenceNode:
+		if t.Kind() == reflect.Slice {
+			for _, item := range node.Content {
+				checkFields(item, t.Elem(), errs)
+			}
+		}
+	case yaml.MappingNode:
+		if t.Kind() != reflect.Struct {
+			return // e.g. env, any key goes
+		}
+
+		fields := make(map[string]reflect.Type)
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if name != "" && name != "-" {
+				fields[name] = t.Field(i).Type
+			}
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldType, ok := fields[key.Value]
+			if !ok {
+				errs.add(key, "unknown field %q%s", key.Value, suggestField(key.Value, fields))
+				continue
+			}
+			checkFields(value, fieldType, errs)
+		}
+	}
+}
+
+// suggestField returns a hint naming the field closest to a misspelled one
+func suggestField(name string, fields map[string]reflect.Type) string {
+	best, bestDistance := "", 3 // Anything further away is no typo
+	for field := range fields {
+		if d := editDistance(name, field); d < bestDistance || (d == bestDistance && field < best) {
+			best, bestDistance = field, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// validate implements `swissknife validate -cfg=...`: it checks the commands
+// files without running anything and returns the exit code, non-zero if any
+// file has a problem, for use in pre-commit hooks
+func validate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	filePaths := flags.String("cfg", "", "comma-separated commands config yaml files to check")
+	vars := make(varFlags)
+	flags.Var(vars, "var", "set a var used in the commands files, as key=value, can be repeated")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *filePaths == "" {
+		fmt.Fprintln(os.Stderr, "no commands files provided")
+		return 2
+	}
+
+	code := 0
+	for _, file := range strings.Split(*filePaths, ",") {
+		if _, _, err := LoadCommandsFromYAML(file, vars); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+			continue
+		}
+		fmt.Printf("%s: ok\n", file)
+	}
+	return code
+}
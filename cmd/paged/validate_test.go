@@ -0,0 +1,143 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestCheckFields(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		t    reflect.Type
+		want []string
+	}{
+		{
+			name: "config",
+			text: "max_paralel: 2\nlayout:\n  rows:\n    - pain: a\n",
+			t:    reflect.TypeOf(YAMLConfig{}),
+			want: []string{
+				`a.yaml:1:1: unknown field "max_paralel", did you mean "max_parallel"?`,
+				`a.yaml:4:7: unknown field "pain", did you mean "pane"?`,
+			},
+		},
+		{
+			name: "command",
+			text: "comand: date\nretry: {attemps: 3}\nenv: {ANYTHING: goes}\n",
+			t:    reflect.TypeOf(YAMLCommand{}),
+			want: []string{
+				`a.yaml:1:1: unknown field "comand", did you mean "command"?`,
+				`a.yaml:2:9: unknown field "attemps", did you mean "attempts"?`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var root yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.text), &root); err != nil {
+				t.Fatal(err)
+			}
+			errs := newConfigErrors("a.yaml")
+			checkFields(root.Content[0], tt.t, errs)
+			if got, want := errs.Error(), strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestSuggestField(t *testing.T) {
+	fields := map[string]reflect.Type{"command": nil, "schedule": nil, "shell": nil}
+	tests := map[string]string{
+		"comand":    `, did you mean "command"?`,
+		"shedule":   `, did you mean "schedule"?`,
+		"shel":      `, did you mean "shell"?`,
+		"something": "",
+	}
+	for name, want := range tests {
+		if got := suggestField(name, fields); got != want {
+			t.Errorf("suggestField(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestConfigErrorsReportsOnce(t *testing.T) {
+	node := &yaml.Node{Line: 3, Column: 7}
+	errs := newConfigErrors("a.yaml")
+	errs.add(node, "unknown var %q", "x")
+	errs.add(node, "unknown var %q", "x")
+	errs.add(&yaml.Node{Line: 1, Column: 1}, "first")
+	if got, want := errs.Error(), "a.yaml:1:1: first\na.yaml:3:7: unknown var \"x\""; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLoadCommandsReportsEveryProblem(t *testing.T) {
+	file := writeConfig(t, "a.yaml", `
+commands:
+  - name: a
+    command: date
+    repeat: soon
+    mode: streem
+  - name: a
+    command: ""
+    overlap: never
+`)
+
+	_, _, err := LoadCommandsFromYAML(file, nil)
+	if err == nil {
+		t.Fatal("loaded an invalid file")
+	}
+	want := []string{
+		`a.yaml:5:13: invalid duration "soon", expected something like 5s, 500ms or 2m30s`,
+		`a.yaml:6:11: unknown mode "streem", expected stream`,
+		`a.yaml:7:11: name "a" is already used on line 3`,
+		`a.yaml:8:14: command "a" has nothing to run`,
+		`a.yaml:9:14: unknown overlap policy "never", expected skip, queue or kill-previous`,
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("got %d problems, want %d:\n%v", len(lines), len(want), err)
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, want[i]) {
+			t.Errorf("problem %d = %q, want %q", i, line, want[i])
+		}
+	}
+}
+
+func TestLoadCommandsStreamSettings(t *testing.T) {
+	file := writeConfig(t, "a.yaml", `
+commands:
+  - name: logs
+    command: tail -f app.log
+    mode: stream
+    repeat: 5s
+    timeout: 1m
+    retry: {attempts: 3}
+    highlight_changes: true
+`)
+
+	_, _, err := LoadCommandsFromYAML(file, nil)
+	if err == nil {
+		t.Fatal("loaded a stream with settings it ignores")
+	}
+	want := []string{
+		`a.yaml:6:13: a stream can't have repeat`,
+		`a.yaml:7:14: a stream can't have timeout`,
+		`a.yaml:8:12: a stream can't have retry`,
+		`a.yaml:9:24: a stream can't have highlight_changes`,
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("got %d problems, want %d:\n%v", len(lines), len(want), err)
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, want[i]) {
+			t.Errorf("problem %d = %q, want %q", i, line, want[i])
+		}
+	}
+}
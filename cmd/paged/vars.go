@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// varEnvPrefix names the environment variables overriding vars, e.g.
+// SWISSKNIFE_VAR_NAMESPACE=prod for `namespace`
+const varEnvPrefix = "SWISSKNIFE_VAR_"
+
+// varFlags collects repeated `-var key=value` flags
+type varFlags map[string]string
+
+func (v varFlags) String() string {
+	pairs := make([]string, 0, len(v))
+	for key, value := range v {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (v varFlags) Set(pair string) error {
+	key, value, ok := strings.Cut(pair, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", pair)
+	}
+	v[key] = value
+	return nil
+}
+
+// resolveVars returns the vars declared in the commands files, overridden
+// by environment variables, in turn overridden by -var flags. It returns nil
+// when there are none, so that commands written before vars existed, e.g.
+// `docker ps --format '{{.Names}}'`, are left alone
+func resolveVars(declared map[string]string, overrides map[string]string) map[string]string {
+	if len(declared) == 0 && len(overrides) == 0 {
+		return nil
+	}
+
+	vars := make(map[string]string, len(declared))
+	maps.Copy(vars, declared)
+	for name := range declared {
+		if value, ok := os.LookupEnv(varEnvPrefix + strings.ToUpper(name)); ok {
+			vars[name] = value
+		}
+	}
+	maps.Copy(vars, overrides)
+	return vars
+}
+
+// expandVars runs text through text/template with vars as its data, so
+// that `kubectl get pods -n {{.namespace}}` picks up the namespace var.
+// Using a var that isn't declared is an error. With nil vars, text is
+// returned as is
+func expandVars(text string, vars map[string]string) (string, error) {
+	if vars == nil || !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %s", templateProblem.ReplaceAllString(err.Error(), ""))
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		if match := missingVar.FindStringSubmatch(err.Error()); match != nil {
+			return "", fmt.Errorf("unknown var %s", match[1])
+		}
+		return "", fmt.Errorf("invalid template: %s", templateProblem.ReplaceAllString(err.Error(), ""))
+	}
+	return b.String(), nil
+}
+
+var (
+	// templateProblem matches the `template: :1:2: ` text/template puts
+	// before its errors, which only makes sense for named template files
+	templateProblem = regexp.MustCompile(`^template: :[0-9:]*:? *(executing "" at <[^>]*>: )?`)
+	// missingVar matches the error of a var that isn't declared
+	missingVar = regexp.MustCompile(`map has no entry for key ("[^"]*")`)
+)
+
+// expandCommandVars expands vars in the name, command, cwd and env of a
+// command, reporting problems at the field they are in
+func expandCommandVars(yamlCmd *YAMLCommand, node *yaml.Node, vars map[string]string, errs *configErrors) {
+	expand := func(text *string, at *yaml.Node) {
+		expanded, err := expandVars(*text, vars)
+		if err != nil {
+			errs.add(at, "%v", err)
+			return
+		}
+		*text = expanded
+	}
+
+	expand(&yamlCmd.Name, fieldNode(node, "name"))
+	expand(&yamlCmd.Command.Script, fieldNode(node, "command"))
+	for i := range yamlCmd.Command.Argv {
+		expand(&yamlCmd.Command.Argv[i], sequenceItem(fieldNode(node, "command"), i))
+	}
+	expand(&yamlCmd.Cwd, fieldNode(node, "cwd"))
+	expandEnvVars(yamlCmd.Env, fieldNode(node, "env"), vars, errs)
+}
+
+// expandDefaultsVars expands vars in the cwd and env of a file's defaults
+func expandDefaultsVars(defaults *YAMLDefaults, node *yaml.Node, vars map[string]string, errs *configErrors) {
+	cwd, err := expandVars(defaults.Cwd, vars)
+	if err != nil {
+		errs.add(fieldNode(node, "cwd"), "%v", err)
+	}
+	defaults.Cwd = cwd
+	expandEnvVars(defaults.Env, fieldNode(node, "env"), vars, errs)
+}
+
+func expandEnvVars(env map[string]string, node *yaml.Node, vars map[string]string, errs *configErrors) {
+	for key, value := range env {
+		expanded, err := expandVars(value, vars)
+		if err != nil {
+			errs.add(fieldNode(node, key), "%v", err)
+			continue
+		}
+		env[key] = expanded
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandVars(t *testing.T) {
+	vars := map[string]string{"ns": "prod"}
+	tests := []struct {
+		name string
+		text string
+		vars map[string]string
+		want string
+		err  string
+	}{
+		{name: "plain text", text: "kubectl get pods", vars: vars, want: "kubectl get pods"},
+		{name: "var", text: "kubectl get pods -n {{.ns}}", vars: vars, want: "kubectl get pods -n prod"},
+		{name: "no vars leaves templates alone", text: "docker ps --format '{{.Names}}'", want: "docker ps --format '{{.Names}}'"},
+		{name: "escaped braces", text: `docker ps --format '{{"{{"}}.Names{{"}}"}}'`, vars: vars, want: "docker ps --format '{{.Names}}'"},
+		{name: "unknown var", text: "{{.Names}}", vars: vars, err: `unknown var "Names"`},
+		{name: "invalid template", text: "{{.ns", vars: vars, err: "invalid template: unclosed action"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandVars(tt.text, tt.vars)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expandVars(%q) error = %v, want %q", tt.text, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandVars(%q) error = %v", tt.text, err)
+			}
+			if got != tt.want {
+				t.Errorf("expandVars(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveVars(t *testing.T) {
+	if vars := resolveVars(nil, nil); vars != nil {
+		t.Errorf("resolveVars(nil, nil) = %v, want nil", vars)
+	}
+
+	t.Setenv("SWISSKNIFE_VAR_NS", "env")
+	t.Setenv("SWISSKNIFE_VAR_DIR", "env")
+	declared := map[string]string{"ns": "file", "dir": "file", "user": "file"}
+	vars := resolveVars(declared, map[string]string{"dir": "flag"})
+	want := map[string]string{"ns": "env", "dir": "flag", "user": "file"}
+	for name, value := range want {
+		if vars[name] != value {
+			t.Errorf("var %s = %q, want %q", name, vars[name], value)
+		}
+	}
+}
+
+func TestLoadCommandsVars(t *testing.T) {
+	file := writeConfig(t, "a.yaml", `
+commands:
+  - name: containers
+    command: "docker ps --format '{{.Names}}'"
+`)
+	commands, _, err := LoadCommandsFromYAML(file, nil)
+	if err != nil {
+		t.Fatalf("without vars: %v", err)
+	}
+	if got := commands[0].Command; got != "docker ps --format '{{.Names}}'" {
+		t.Errorf("without vars, command = %q", got)
+	}
+
+	_, _, err = LoadCommandsFromYAML(file, map[string]string{"ns": "prod"})
+	if err == nil || !strings.Contains(err.Error(), `a.yaml:4:14: unknown var "Names"`) {
+		t.Errorf("with -var, error = %v, want unknown var", err)
+	}
+
+	file = writeConfig(t, "a.yaml", `
+vars:
+  ns: staging
+defaults:
+  env:
+    NAMESPACE: "{{.ns}}"
+commands:
+  - name: "Pods {{.ns}}"
+    command: "kubectl get pods -n {{.ns}}"
+`)
+	commands, _, err = LoadCommandsFromYAML(file, map[string]string{"ns": "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := commands[0]
+	if cmd.Name != "Pods prod" || cmd.Command != "kubectl get pods -n prod" || !strings.Contains(strings.Join(cmd.Env, " "), "NAMESPACE=prod") {
+		t.Errorf("got name %q, command %q, env %v", cmd.Name, cmd.Command, cmd.Env)
+	}
+}
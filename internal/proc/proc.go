@@ -0,0 +1,70 @@
+// Package proc runs the processes behind the panes of the dashboards and
+// hands their results to the UI thread
+package proc
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// KillGracePeriod is how long a cancelled process group gets to exit after
+// SIGTERM before it is sent SIGKILL
+const KillGracePeriod = 3 * time.Second
+
+// Run starts execCmd in its own process group and waits for it to finish.
+// If ctx is cancelled first, the whole group is terminated so that children
+// spawned by `sh -c` don't outlive the pane, and ctx.Err() is returned.
+func Run(ctx context.Context, execCmd *exec.Cmd) error {
+	execCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := execCmd.Start(); err != nil {
+		return err
+	}
+	return Wait(ctx, execCmd, execCmd.Wait)
+}
+
+// Wait waits for the started execCmd to finish through wait, terminating
+// its process group if ctx is cancelled first
+func Wait(ctx context.Context, execCmd *exec.Cmd, wait func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	pgid := -execCmd.Process.Pid
+	_ = syscall.Kill(pgid, syscall.SIGTERM)
+
+	select {
+	case <-done:
+	case <-time.After(KillGracePeriod):
+		_ = syscall.Kill(pgid, syscall.SIGKILL)
+		<-done
+	}
+
+	return ctx.Err()
+}
+
+// QueueDraw runs f on the UI thread. QueueUpdateDraw blocks until the event
+// loop picks the update up, which never happens once the app has stopped, so
+// we stop waiting as soon as ctx is done.
+func QueueDraw(ctx context.Context, app *tview.Application, f func()) {
+	done := make(chan struct{})
+	go func() {
+		app.QueueUpdateDraw(f)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,33 @@
+package proc
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	err := Run(context.Background(), exec.Command("sh", "-c", "exit 3"))
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("Run = %v, want exit status 3", err)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The shell waits on its child, which only returns once the whole group
+	// got SIGTERM
+	started := time.Now()
+	err := Run(ctx, exec.Command("sh", "-c", "sleep 30 & wait"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(started); elapsed >= KillGracePeriod {
+		t.Errorf("Run took %v, the process group wasn't terminated", elapsed)
+	}
+}